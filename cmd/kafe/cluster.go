@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/clemsau/kafe/internal/deserialize"
+	"github.com/clemsau/kafe/internal/kafka"
+)
+
+// clusterFlags selects the cluster context a command connects to
+type clusterFlags struct {
+	flags      *flag.FlagSet
+	configPath *string
+	context    *string
+	brokers    *string
+}
+
+func addClusterFlags(flags *flag.FlagSet) *clusterFlags {
+	defaultPath, _ := config.DefaultPath()
+	return &clusterFlags{
+		flags:      flags,
+		configPath: flags.String("config", defaultPath, "configuration file"),
+		context:    flags.String("context", "", "cluster context from the configuration file (default: the current context)"),
+		brokers:    flags.String("brokers", defaultBrokers, "comma-separated list of brokers, overrides the context's brokers"),
+	}
+}
+
+// load reads the configuration file
+func (f *clusterFlags) load() (*config.Config, error) {
+	return config.Load(*f.configPath)
+}
+
+// resolve returns the selected context, falling back to the brokers flag
+// when the configuration has no context
+func (f *clusterFlags) resolve() (config.Context, error) {
+	cfg, err := f.load()
+	if err != nil {
+		return config.Context{}, err
+	}
+
+	ctx, ok := cfg.Context(*f.context)
+	if !ok && *f.context != "" {
+		return config.Context{}, fmt.Errorf("unknown context %q", *f.context)
+	}
+	if !ok {
+		ctx = config.Context{Name: "default"}
+	}
+
+	brokersSet := false
+	f.flags.Visit(func(fl *flag.Flag) {
+		brokersSet = brokersSet || fl.Name == "brokers"
+	})
+	if brokersSet || len(ctx.Brokers) == 0 {
+		ctx.Brokers = strings.Split(*f.brokers, ",")
+	}
+	return ctx, nil
+}
+
+// named returns a context of the configuration file by name
+func (f *clusterFlags) named(name string) (config.Context, error) {
+	cfg, err := f.load()
+	if err != nil {
+		return config.Context{}, err
+	}
+
+	ctx, ok := cfg.Context(name)
+	if !ok || name == "" {
+		return config.Context{}, fmt.Errorf("unknown context %q", name)
+	}
+	return ctx, nil
+}
+
+// connect creates a client for the selected context
+func (f *clusterFlags) connect() (*kafka.Client, config.Context, error) {
+	ctx, err := f.resolve()
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	deserializers, err := deserialize.NewDeserializers(ctx)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	client, err := kafka.NewClient(ctx.Brokers, nil)
+	if err != nil {
+		return nil, ctx, err
+	}
+	client.SetDecoder(deserializers)
+	return client, ctx, nil
+}
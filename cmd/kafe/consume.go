@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/filter"
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/records"
+)
+
+// runConsume implements `kafe consume`, a headless bounded export of a topic
+func runConsume(args []string) int {
+	flags := flag.NewFlagSet("consume", flag.ExitOnError)
+	cluster := addClusterFlags(flags)
+	topic := flags.String("topic", "", "topic to consume (required)")
+	output := flags.String("output", "-", `file to write, a directory for the raw format, or "-" for the standard output`)
+	format := flags.String("format", "", "output format, jsonl, csv or raw (default: from the file extension, jsonl for the standard output)")
+	from := flags.String("from", "earliest", "start of the range: a time such as -6h or 2024-01-01, earliest, latest or @offset")
+	to := flags.String("to", "latest", "end of the range, captured at launch")
+	expression := flags.String("filter", "", "filter expression messages must match")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), "Usage: kafe consume --topic TOPIC [--output FILE] [flags]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *topic == "" {
+		flags.Usage()
+		return 2
+	}
+
+	formatName := *format
+	if formatName == "" && *output == "-" {
+		formatName = string(records.FormatJSONL)
+	}
+	outputFormat, err := records.ParseFormat(formatName, *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	now := time.Now()
+	start, err := kafka.ParsePosition(*from, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "from: %v\n", err)
+		return 2
+	}
+	end, err := kafka.ParsePosition(*to, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "to: %v\n", err)
+		return 2
+	}
+	f, err := filter.ParseAt(*expression, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "filter: %v\n", err)
+		return 2
+	}
+
+	client, _, err := cluster.connect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	search, err := client.NewSearch(*topic, start, end, f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	writer, err := records.NewFileWriter(*output, outputFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var mutex sync.Mutex
+	done := make(chan struct{})
+	go reportProgress(search, done)
+
+	err = search.Run(ctx, func(msg models.Message) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err := writer.Write(&msg); err != nil {
+			cancel(err)
+		}
+	})
+	close(done)
+
+	if closeErr := writer.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if cause := context.Cause(ctx); cause != nil {
+		err = cause
+	}
+
+	progress := search.Progress()
+	fmt.Fprintf(os.Stderr, "\rscanned %d, exported %d in %s\n", progress.Scanned, progress.Matched, progress.Elapsed.Round(time.Millisecond))
+	for _, partition := range progress.Partitions {
+		if partition.Err != nil {
+			fmt.Fprintf(os.Stderr, "partition %d: %v\n", partition.Partition, partition.Err)
+			err = partition.Err
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func reportProgress(search *kafka.Search, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			progress := search.Progress()
+			fmt.Fprintf(os.Stderr, "\rscanned %d/%d, exported %d, ETA %s  ",
+				progress.Scanned, progress.Total, progress.Matched, progress.ETA().Round(time.Second))
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/clemsau/kafe/internal/filter"
+	"github.com/clemsau/kafe/internal/kafka"
+)
+
+// runCopy implements `kafe copy`, a headless copy of a range of a topic to
+// another topic, possibly on another cluster context
+func runCopy(args []string) int {
+	flags := flag.NewFlagSet("copy", flag.ExitOnError)
+	cluster := addClusterFlags(flags)
+	topic := flags.String("topic", "", "topic to copy from (required)")
+	toTopic := flags.String("to-topic", "", "topic to copy to (default: the source topic, on another context)")
+	toContext := flags.String("to-context", "", "cluster context to copy to (default: the source cluster)")
+	from := flags.String("from", "earliest", "start of the range: a time such as -6h or 2024-01-01, earliest, latest or @offset")
+	to := flags.String("to", "latest", "end of the range, captured at launch")
+	expression := flags.String("filter", "", "filter expression messages must match")
+	keepPartitions := flags.Bool("keep-partitions", false, "produce each message to its source partition")
+	keepTimestamps := flags.Bool("keep-timestamps", false, "preserve the source timestamps")
+	rate := flags.Float64("rate", 0, "maximum records per second, 0 for unlimited")
+	partitioner := flags.String("partitioner", "hash", "partitioner when partitions are not kept: "+strings.Join(kafka.Partitioners, ", "))
+	compression := flags.String("compression", "none", "compression codec: "+strings.Join(kafka.Compressions, ", "))
+	idempotent := flags.Bool("idempotent", false, "use an idempotent producer")
+	transactionalID := flags.String("transactional-id", "", "produce in transactions of up to 500 records with this transactional ID")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), `Usage: kafe copy --topic TOPIC [--to-topic TOPIC] [--to-context CONTEXT] [flags]
+
+Keys, values and headers are copied as is. The source cluster is selected with
+--context and --brokers, the destination one with --to-context.
+
+Flags:
+`)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *toTopic == "" {
+		*toTopic = *topic
+	}
+	if *topic == "" || (*toTopic == *topic && *toContext == "") {
+		flags.Usage()
+		return 2
+	}
+
+	now := time.Now()
+	start, err := kafka.ParsePosition(*from, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "from: %v\n", err)
+		return 2
+	}
+	end, err := kafka.ParsePosition(*to, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "to: %v\n", err)
+		return 2
+	}
+	f, err := filter.ParseAt(*expression, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "filter: %v\n", err)
+		return 2
+	}
+
+	client, _, err := cluster.connect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	dest := client
+	if *toContext != "" {
+		destContext, err := cluster.named(*toContext)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		dest, err = kafka.NewClient(destContext.Brokers, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer dest.Close()
+	}
+
+	job, err := client.NewCopyJob(*topic, dest, *toTopic, kafka.CopyOptions{
+		BulkOptions: kafka.BulkOptions{
+			ProducerOptions: kafka.ProducerOptions{
+				Partitioner:     *partitioner,
+				Compression:     *compression,
+				Idempotent:      *idempotent,
+				TransactionalID: *transactionalID,
+			},
+			Rate: *rate,
+		},
+		From:           start,
+		To:             end,
+		Filter:         f,
+		KeepPartitions: *keepPartitions,
+		KeepTimestamps: *keepTimestamps,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := job.Run(ctx, func(progress kafka.BulkResult) {
+		scan := job.Progress()
+		fmt.Fprintf(os.Stderr, "\rscanned %d/%d, delivered %d, failed %d  ",
+			scan.Scanned, scan.Total, progress.Delivered, progress.Failed)
+	})
+	fmt.Fprintln(os.Stderr)
+
+	for _, partition := range job.Progress().Partitions {
+		if partition.Err != nil {
+			fmt.Fprintf(os.Stderr, "partition %d: %v\n", partition.Partition, partition.Err)
+			if err == nil {
+				err = partition.Err
+			}
+		}
+	}
+
+	printBulkSummary(result)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
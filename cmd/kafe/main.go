@@ -1,25 +1,79 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 
-	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/clemsau/kafe/internal/metrics"
 	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/rules"
 	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/messages"
 	"github.com/clemsau/kafe/internal/ui/topics"
+	"github.com/clemsau/kafe/internal/ui/watchlist"
+	"github.com/clemsau/kafe/internal/watch"
 )
 
+const defaultBrokers = "localhost:9092"
+
 func main() {
-	client, err := kafka.NewClient([]string{"localhost:9092"}, nil)
+	cluster := addClusterFlags(flag.CommandLine)
+	flag.IntVar(&messages.DefaultBuffer.MaxMessages, "buffer-messages", messages.DefaultBuffer.MaxMessages, "messages kept by the message viewer")
+	flag.IntVar(&messages.DefaultBuffer.MaxBytes, "buffer-bytes", messages.DefaultBuffer.MaxBytes, "bytes of keys, values and headers kept by the message viewer, 0 for no limit")
+	metricsWindow := flag.Duration("metrics-window", metrics.DefaultWindow, "history of topic and consumer group statistics kept for rates and trends")
+	flag.Usage = usage
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "produce":
+			os.Exit(runProduce(os.Args[2:]))
+		case "consume":
+			os.Exit(runConsume(os.Args[2:]))
+		case "copy":
+			os.Exit(runCopy(os.Args[2:]))
+		case "help":
+			usage()
+			return
+		}
+	}
+
+	flag.Parse()
+
+	client, clusterContext, err := cluster.connect()
 	if err != nil {
 		log.Fatalf("Error creating client: %v", err)
 	}
 	defer client.Close()
 
+	cfg, err := cluster.load()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	statusRules, err := rules.New(clusterContext.Rules)
+	if err != nil {
+		log.Fatalf("Error in the rules of context %s: %v", clusterContext.Name, err)
+	}
+
+	watchList, err := loadWatchList(clusterContext)
+	if err != nil {
+		log.Fatalf("Error loading watch list: %v", err)
+	}
+
 	cache := models.NewTopicCache()
+	history := metrics.NewStore(*metricsWindow)
+	go history.PollGroups(context.Background(), client.GetConsumerGroups)
 
 	app := ui.NewApp()
-	layout := topics.NewTable(app, client, cache)
+	app.SetContexts(cfg.Contexts, clusterContext.Name)
+	app.SetRules(statusRules)
+	app.SetWatchList(watchList)
+	watcher := watchlist.NewWatcher(app, cache, history)
+	watcher.Start()
+	layout := topics.NewTable(app, client, cache, history, watcher)
 
 	app.AddPage("topics", layout, true)
 
@@ -29,3 +83,27 @@ func main() {
 		log.Fatalf("Error running application: %v", err)
 	}
 }
+
+// loadWatchList loads the watch list of a context. The list is not saved
+// when there is no state directory.
+func loadWatchList(ctx config.Context) (*watch.List, error) {
+	path, err := watch.DefaultPath()
+	if err != nil {
+		path = ""
+	}
+	return watch.Load(path, ctx.Name)
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage:
+  kafe [flags]         start the TUI
+  kafe produce [flags] produce records from a JSON lines or CSV file
+  kafe consume [flags] export a range of a topic to JSON lines, CSV or raw files
+  kafe copy [flags]    copy a range of a topic to another topic, possibly on another context
+
+Run "kafe <command> -h" for the flags of a command.
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
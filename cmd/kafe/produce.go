@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/records"
+)
+
+// runProduce implements `kafe produce`, a headless bulk produce from a file
+func runProduce(args []string) int {
+	flags := flag.NewFlagSet("produce", flag.ExitOnError)
+	cluster := addClusterFlags(flags)
+	topic := flags.String("topic", "", "topic to produce to (required)")
+	file := flags.String("file", "", "JSON lines or CSV file to read records from (required)")
+	format := flags.String("format", "", "file format, jsonl or csv (default: from the file extension)")
+	rate := flags.Float64("rate", 0, "maximum records per second, 0 for unlimited")
+	partitioner := flags.String("partitioner", "hash", "partitioner for records without a partition: "+strings.Join(kafka.Partitioners, ", "))
+	compression := flags.String("compression", "none", "compression codec: "+strings.Join(kafka.Compressions, ", "))
+	idempotent := flags.Bool("idempotent", false, "use an idempotent producer")
+	transactionalID := flags.String("transactional-id", "", "produce in transactions of up to 500 records with this transactional ID")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), `Usage: kafe produce --topic TOPIC --file FILE [flags]
+
+Each JSON line is an object such as
+  {"key": "order-42", "value": {"status": "NEW"}, "headers": {"source": "replay"}, "partition": 0}
+where the value may be any JSON document, and key_base64 / value_base64 carry binary data.
+CSV files start with a header row naming the key, value, headers (a JSON object) and partition columns.
+
+Flags:
+`)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *topic == "" || *file == "" {
+		flags.Usage()
+		return 2
+	}
+
+	recordFormat, err := records.ParseFormat(*format, *file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	reader, err := records.NewReader(f, recordFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	client, _, err := cluster.connect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts := kafka.BulkOptions{
+		ProducerOptions: kafka.ProducerOptions{
+			Partitioner:     *partitioner,
+			Compression:     *compression,
+			Idempotent:      *idempotent,
+			TransactionalID: *transactionalID,
+		},
+		Rate: *rate,
+	}
+
+	result, err := client.ProduceBulk(ctx, *topic, reader.Next, opts, func(progress kafka.BulkResult) {
+		fmt.Fprintf(os.Stderr, "\rdelivered %d, failed %d", progress.Delivered, progress.Failed)
+	})
+	fmt.Fprintln(os.Stderr)
+
+	printBulkSummary(result)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printBulkSummary(result kafka.BulkResult) {
+	fmt.Printf("Delivered: %d\nFailed:    %d\nElapsed:   %s\n", result.Delivered, result.Failed, result.Elapsed.Round(time.Millisecond))
+	if result.Failed == 0 {
+		return
+	}
+
+	fmt.Println("\nFailure reasons:")
+	for _, reason := range result.SortedReasons() {
+		fmt.Printf("  %6d  %s\n", result.Reasons[reason], reason)
+	}
+
+	fmt.Println("\nFailed records:")
+	for _, failure := range result.Failures {
+		fmt.Printf("  line %d: %v\n", failure.Line, failure.Err)
+	}
+	if result.Failed > len(result.Failures) {
+		fmt.Printf("  ... and %d more\n", result.Failed-len(result.Failures))
+	}
+}
@@ -4,8 +4,11 @@ go 1.22.0
 
 require (
 	github.com/IBM/sarama v1.43.3
+	github.com/bufbuild/protocompile v0.14.1
 	github.com/gdamore/tcell/v2 v2.7.4
+	github.com/linkedin/goavro/v2 v2.12.0
 	github.com/rivo/tview v0.0.0-20241016194538-c5e4fb24af13
+	google.golang.org/protobuf v1.34.2
 )
 
 require (
@@ -31,6 +34,7 @@ require (
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/crypto v0.28.0 // indirect
 	golang.org/x/net v0.30.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.26.0 // indirect
 	golang.org/x/term v0.25.0 // indirect
 	golang.org/x/text v0.19.0 // indirect
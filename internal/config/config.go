@@ -0,0 +1,169 @@
+// Package config loads the kafe configuration file describing the cluster
+// contexts the user can connect to.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Config is the content of the configuration file, by default
+// $XDG_CONFIG_HOME/kafe/config.json:
+//
+//	{
+//	  "current-context": "local",
+//	  "contexts": [
+//	    {
+//	      "name": "local",
+//	      "brokers": ["localhost:9092"],
+//	      "schema-registry": {
+//	        "url": "https://registry:8081",
+//	        "username": "user",
+//	        "password": "secret",
+//	        "tls": {"ca-file": "/path/to/ca.pem"}
+//	      },
+//	      "protobuf": {
+//	        "import-paths": ["/path/to/protos"],
+//	        "topics": [
+//	          {"topic": "orders", "files": ["shop/order.proto"], "value-message": "shop.Order"}
+//	        ]
+//	      },
+//	      "deserializers": [
+//	        {"topic": "orders-*", "key": "string", "value": "avro"}
+//	      ],
+//	      "rules": [
+//	        {"group": "billing-*", "max-time-lag": "1m", "status": "Critical", "color": "red"},
+//	        {"topic": "*", "min-in-sync": 2, "status": "Warning"}
+//	      ]
+//	    }
+//	  ]
+//	}
+type Config struct {
+	CurrentContext string    `json:"current-context,omitempty"`
+	Contexts       []Context `json:"contexts"`
+}
+
+// Context describes a cluster and the services attached to it
+type Context struct {
+	Name           string                `json:"name"`
+	Brokers        []string              `json:"brokers"`
+	SchemaRegistry *SchemaRegistryConfig `json:"schema-registry,omitempty"`
+	Protobuf       *ProtobufConfig       `json:"protobuf,omitempty"`
+	Deserializers  []DeserializerMapping `json:"deserializers,omitempty"`
+	Rules          []Rule                `json:"rules,omitempty"`
+}
+
+// Rule sets the status of the topics or consumer groups matching glob
+// patterns when one of its thresholds is exceeded. A rule with a group
+// pattern applies to the groups consuming the matching topics, a rule
+// without to the topics. The first exceeded rule wins.
+type Rule struct {
+	Topic string `json:"topic,omitempty"`
+	Group string `json:"group,omitempty"`
+
+	// MaxLag is the number of records a group may lag behind
+	MaxLag int64 `json:"max-lag,omitempty"`
+	// MaxTimeLag is how far behind in time a group may be, e.g. "5m"
+	MaxTimeLag string `json:"max-time-lag,omitempty"`
+	// MaxLagGrowth is how many records per second the lag of a group may
+	// grow by over GrowthWindow, "5m" by default
+	MaxLagGrowth float64 `json:"max-lag-growth,omitempty"`
+	GrowthWindow string  `json:"growth-window,omitempty"`
+
+	// UnderReplicated flags topics with partitions missing in-sync replicas
+	UnderReplicated bool `json:"under-replicated,omitempty"`
+	// MinInSync is the fewest in-sync replicas a partition may have
+	MinInSync int `json:"min-in-sync,omitempty"`
+
+	Status string `json:"status"`
+	// Color is the color of the status, a name such as "red" or a hex
+	// code such as "#ff8800"
+	Color string `json:"color,omitempty"`
+}
+
+// DeserializerMapping selects the deserializers of the keys and values of
+// the topics matching a glob pattern, the first matching mapping winning.
+// An empty deserializer means auto.
+type DeserializerMapping struct {
+	Topic string `json:"topic"`
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// SchemaRegistryConfig locates a Confluent compatible schema registry
+type SchemaRegistryConfig struct {
+	URL      string    `json:"url"`
+	Username string    `json:"username,omitempty"`
+	Password string    `json:"password,omitempty"`
+	TLS      TLSConfig `json:"tls,omitempty"`
+}
+
+// ProtobufConfig maps topics holding raw protobuf messages, not framed in
+// the schema registry wire format, to local schemas
+type ProtobufConfig struct {
+	ImportPaths []string        `json:"import-paths,omitempty"`
+	Topics      []ProtobufTopic `json:"topics"`
+}
+
+// ProtobufTopic gives the messages of a topic's keys and values, defined in
+// .proto files found in the import paths or in a descriptor set
+type ProtobufTopic struct {
+	Topic         string   `json:"topic"`
+	Files         []string `json:"files,omitempty"`
+	DescriptorSet string   `json:"descriptor-set,omitempty"`
+	KeyMessage    string   `json:"key-message,omitempty"`
+	ValueMessage  string   `json:"value-message,omitempty"`
+}
+
+// TLSConfig configures TLS for an HTTP or Kafka connection
+type TLSConfig struct {
+	CAFile             string `json:"ca-file,omitempty"`
+	CertFile           string `json:"cert-file,omitempty"`
+	KeyFile            string `json:"key-file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure-skip-verify,omitempty"`
+}
+
+// DefaultPath returns the default location of the configuration file
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kafe", "config.json"), nil
+}
+
+// Load reads the configuration file at path. A missing file yields an
+// empty configuration.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Context returns the named context, or the current context when name is
+// empty. It returns false when the configuration has no such context.
+func (c *Config) Context(name string) (Context, bool) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name || (name == "" && len(c.Contexts) > 0) {
+			return ctx, true
+		}
+	}
+	return Context{}, false
+}
@@ -0,0 +1,179 @@
+// Package deserialize renders the keys and values of records as text, with
+// built-in deserializers, the schema registry and local protobuf schemas.
+// It is shared by the message viewer and the headless commands, which is
+// why it lives outside the UI packages.
+package deserialize
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/clemsau/kafe/internal/protobuf"
+	"github.com/clemsau/kafe/internal/schemaregistry"
+)
+
+// Field tells whether a deserializer decodes a record key or value
+type Field int
+
+const (
+	KeyField Field = iota
+	ValueField
+)
+
+func (f Field) String() string {
+	if f == KeyField {
+		return "key"
+	}
+	return "value"
+}
+
+// ErrSkipped is returned by deserializers leaving the data as is
+var ErrSkipped = errors.New("left undecoded")
+
+// Deserializer renders the key or value of a record as text, JSON when the
+// data is structured so filters can reach into it
+type Deserializer interface {
+	Name() string
+	Deserialize(topic string, field Field, data []byte) (string, error)
+}
+
+// Names of the built-in deserializers
+const (
+	DeserializerAuto     = "auto"
+	DeserializerString   = "string"
+	DeserializerJSON     = "json"
+	DeserializerHex      = "hex"
+	DeserializerInt      = "int"
+	DeserializerLong     = "long"
+	DeserializerDouble   = "double"
+	DeserializerUUID     = "uuid"
+	DeserializerAvro     = "avro"
+	DeserializerProtobuf = "protobuf"
+)
+
+// deserializerFunc is a deserializer that needs neither the topic nor the field
+type deserializerFunc struct {
+	name        string
+	deserialize func(data []byte) (string, error)
+}
+
+func (d deserializerFunc) Name() string {
+	return d.name
+}
+
+func (d deserializerFunc) Deserialize(_ string, _ Field, data []byte) (string, error) {
+	return d.deserialize(data)
+}
+
+func deserializeString(data []byte) (string, error) {
+	return strings.ToValidUTF8(string(data), "�"), nil
+}
+
+func deserializeJSON(data []byte) (string, error) {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, data); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	return compacted.String(), nil
+}
+
+func deserializeHex(data []byte) (string, error) {
+	return hex.EncodeToString(data), nil
+}
+
+func deserializeInt(data []byte) (string, error) {
+	if len(data) != 4 {
+		return "", fmt.Errorf("int needs 4 bytes, got %d", len(data))
+	}
+	return strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(data))), 10), nil
+}
+
+func deserializeLong(data []byte) (string, error) {
+	if len(data) != 8 {
+		return "", fmt.Errorf("long needs 8 bytes, got %d", len(data))
+	}
+	return strconv.FormatInt(int64(binary.BigEndian.Uint64(data)), 10), nil
+}
+
+func deserializeDouble(data []byte) (string, error) {
+	if len(data) != 8 {
+		return "", fmt.Errorf("double needs 8 bytes, got %d", len(data))
+	}
+	return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(data)), 'g', -1, 64), nil
+}
+
+func deserializeUUID(data []byte) (string, error) {
+	if len(data) != 16 {
+		return "", fmt.Errorf("uuid needs 16 bytes, got %d", len(data))
+	}
+	text := hex.EncodeToString(data)
+	return text[0:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:], nil
+}
+
+// registryDeserializer decodes data framed in the schema registry wire
+// format with a schema of the given type. Protobuf data that is not framed
+// is decoded with the local message mapped to the topic, if any.
+type registryDeserializer struct {
+	name       string
+	schemaType string
+	registry   *schemaregistry.Client
+	local      map[string]localProtobuf
+}
+
+func (d *registryDeserializer) Name() string {
+	return d.name
+}
+
+func (d *registryDeserializer) Deserialize(topic string, field Field, data []byte) (string, error) {
+	id, _, err := schemaregistry.ParseWireFormat(data)
+	if errors.Is(err, schemaregistry.ErrNotFramed) {
+		if md := d.local[topic].message(field); md != nil && d.schemaType == schemaregistry.TypeProtobuf {
+			return protobuf.Decode(md, data)
+		}
+		return "", err
+	}
+	if d.registry == nil {
+		return "", errors.New("no schema registry configured")
+	}
+
+	schema, err := d.registry.Schema(id)
+	if err != nil {
+		return "", err
+	}
+	if schema.Type != d.schemaType {
+		return "", fmt.Errorf("schema %d is %s, not %s", id, schema.Type, d.schemaType)
+	}
+	return d.registry.Decode(data)
+}
+
+// autoDeserializer decodes framed data with the schema registry and
+// unframed data with the local protobuf message mapped to the topic, and
+// leaves other data as is
+type autoDeserializer struct {
+	registry *schemaregistry.Client
+	local    map[string]localProtobuf
+}
+
+func (d *autoDeserializer) Name() string {
+	return DeserializerAuto
+}
+
+func (d *autoDeserializer) Deserialize(topic string, field Field, data []byte) (string, error) {
+	if d.registry != nil {
+		text, err := d.registry.Decode(data)
+		if !errors.Is(err, schemaregistry.ErrNotFramed) {
+			return text, err
+		}
+	}
+	if md := d.local[topic].message(field); md != nil {
+		return protobuf.Decode(md, data)
+	}
+	return "", ErrSkipped
+}
@@ -0,0 +1,91 @@
+package deserialize
+
+import (
+	"testing"
+
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/clemsau/kafe/internal/models"
+)
+
+func TestBuiltinDeserializers(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		// wantErr is set when the data has the wrong size
+		wantErr bool
+	}{
+		{name: DeserializerInt, data: []byte{0, 0, 0x01, 0x2c}, want: "300"},
+		{name: DeserializerInt, data: []byte{0xff, 0xff, 0xff, 0xfe}, want: "-2"},
+		{name: DeserializerInt, data: []byte{0, 1}, wantErr: true},
+		{name: DeserializerLong, data: []byte{0, 0, 0, 0x01, 0, 0, 0, 0}, want: "4294967296"},
+		{name: DeserializerLong, data: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, want: "-1"},
+		{name: DeserializerLong, data: []byte{0, 0, 0, 1}, wantErr: true},
+		{name: DeserializerDouble, data: []byte{0x40, 0x29, 0, 0, 0, 0, 0, 0}, want: "12.5"},
+		{name: DeserializerDouble, data: []byte{0xbf, 0xf0, 0, 0, 0, 0, 0, 0}, want: "-1"},
+		{name: DeserializerDouble, data: []byte{0x40, 0x29, 0, 0}, wantErr: true},
+		{
+			name: DeserializerUUID,
+			data: []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00},
+			want: "123e4567-e89b-12d3-a456-426614174000",
+		},
+		{name: DeserializerUUID, data: []byte("123e4567"), wantErr: true},
+		{name: DeserializerHex, data: []byte{0xca, 0xfe}, want: "cafe"},
+		{name: DeserializerJSON, data: []byte(`{ "id": 1 }`), want: `{"id":1}`},
+		{name: DeserializerJSON, data: []byte(`{`), wantErr: true},
+	}
+
+	deserializers, err := NewDeserializers(config.Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		deserializer, ok := deserializers.Get(test.name)
+		if !ok {
+			t.Fatalf("no %s deserializer", test.name)
+		}
+		text, err := deserializer.Deserialize("orders", ValueField, test.data)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s of %x = %s, want an error", test.name, test.data, text)
+			}
+			continue
+		}
+		if err != nil || text != test.want {
+			t.Errorf("%s of %x = %s, %v, want %s", test.name, test.data, text, err, test.want)
+		}
+	}
+}
+
+func TestForTopic(t *testing.T) {
+	deserializers, err := NewDeserializers(config.Context{
+		Deserializers: []config.DeserializerMapping{
+			{Topic: "orders-*", Key: DeserializerString, Value: DeserializerLong},
+			{Topic: "orders-eu", Value: DeserializerJSON},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		topic      string
+		key, value string
+	}{
+		{"orders-eu", DeserializerString, DeserializerLong},
+		{"orders", DeserializerAuto, DeserializerAuto},
+		{"payments", DeserializerAuto, DeserializerAuto},
+	}
+	for _, test := range tests {
+		key, value := deserializers.ForTopic(test.topic)
+		if key.Name() != test.key || value.Name() != test.value {
+			t.Errorf("ForTopic(%s) = %s, %s, want %s, %s", test.topic, key.Name(), value.Name(), test.key, test.value)
+		}
+	}
+
+	msg := models.Message{Topic: "orders-us", Key: []byte("order-42"), Value: []byte{0, 0, 0, 0, 0, 0, 0, 7}}
+	deserializers.DecodeMessage(&msg)
+	if msg.DecodedKey == nil || *msg.DecodedKey != "order-42" || msg.DecodedValue == nil || *msg.DecodedValue != "7" || msg.DecodeErr != nil {
+		t.Errorf("decoded %v=%v (%v), want order-42=7", msg.DecodedKey, msg.DecodedValue, msg.DecodeErr)
+	}
+}
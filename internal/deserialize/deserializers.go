@@ -0,0 +1,182 @@
+package deserialize
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/protobuf"
+	"github.com/clemsau/kafe/internal/schemaregistry"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// localProtobuf holds the local messages of a topic's keys and values
+type localProtobuf struct {
+	key   protoreflect.MessageDescriptor
+	value protoreflect.MessageDescriptor
+}
+
+func (l localProtobuf) message(field Field) protoreflect.MessageDescriptor {
+	if field == KeyField {
+		return l.key
+	}
+	return l.value
+}
+
+// Deserializers holds the deserializers of a cluster context and picks
+// the ones of a topic from the context's mappings
+type Deserializers struct {
+	byName   map[string]Deserializer
+	mappings []config.DeserializerMapping
+}
+
+// NewDeserializers creates the built-in deserializers, with the schema
+// registry and local protobuf schemas of the context
+func NewDeserializers(ctx config.Context) (*Deserializers, error) {
+	var registry *schemaregistry.Client
+	if ctx.SchemaRegistry != nil {
+		var err error
+		if registry, err = schemaregistry.NewClient(*ctx.SchemaRegistry); err != nil {
+			return nil, err
+		}
+	}
+
+	local := make(map[string]localProtobuf)
+	if ctx.Protobuf != nil {
+		for _, topic := range ctx.Protobuf.Topics {
+			messages, err := loadLocalProtobuf(ctx.Protobuf.ImportPaths, topic)
+			if err != nil {
+				return nil, fmt.Errorf("protobuf schema of topic %s: %w", topic.Topic, err)
+			}
+			local[topic.Topic] = messages
+		}
+	}
+
+	d := &Deserializers{
+		byName:   make(map[string]Deserializer),
+		mappings: ctx.Deserializers,
+	}
+	for _, deserializer := range []Deserializer{
+		&autoDeserializer{registry: registry, local: local},
+		deserializerFunc{DeserializerString, deserializeString},
+		deserializerFunc{DeserializerJSON, deserializeJSON},
+		deserializerFunc{DeserializerHex, deserializeHex},
+		deserializerFunc{DeserializerInt, deserializeInt},
+		deserializerFunc{DeserializerLong, deserializeLong},
+		deserializerFunc{DeserializerDouble, deserializeDouble},
+		deserializerFunc{DeserializerUUID, deserializeUUID},
+		&registryDeserializer{name: DeserializerAvro, schemaType: schemaregistry.TypeAvro, registry: registry},
+		&registryDeserializer{name: DeserializerProtobuf, schemaType: schemaregistry.TypeProtobuf, registry: registry, local: local},
+	} {
+		d.byName[deserializer.Name()] = deserializer
+	}
+
+	for _, mapping := range ctx.Deserializers {
+		if _, err := path.Match(mapping.Topic, ""); err != nil {
+			return nil, fmt.Errorf("invalid topic pattern %q: %w", mapping.Topic, err)
+		}
+		for _, name := range []string{mapping.Key, mapping.Value} {
+			if _, ok := d.Get(name); !ok {
+				return nil, fmt.Errorf("unknown deserializer %q for topic %s", name, mapping.Topic)
+			}
+		}
+	}
+	return d, nil
+}
+
+func loadLocalProtobuf(importPaths []string, topic config.ProtobufTopic) (localProtobuf, error) {
+	var files *protobuf.Files
+	var err error
+	switch {
+	case topic.DescriptorSet != "":
+		files, err = protobuf.LoadDescriptorSet(topic.DescriptorSet)
+	case len(topic.Files) > 0:
+		files, err = protobuf.Compile(importPaths, topic.Files...)
+	default:
+		return localProtobuf{}, errors.New("no files or descriptor set")
+	}
+	if err != nil {
+		return localProtobuf{}, err
+	}
+
+	var messages localProtobuf
+	if topic.KeyMessage != "" {
+		if messages.key, err = files.Message(topic.KeyMessage); err != nil {
+			return localProtobuf{}, err
+		}
+	}
+	if topic.ValueMessage != "" {
+		if messages.value, err = files.Message(topic.ValueMessage); err != nil {
+			return localProtobuf{}, err
+		}
+	}
+	return messages, nil
+}
+
+// Names returns the names of the deserializers, auto first
+func (d *Deserializers) Names() []string {
+	names := make([]string, 0, len(d.byName))
+	for name := range d.byName {
+		if name != DeserializerAuto {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DeserializerAuto}, names...)
+}
+
+// Get returns a deserializer by name, an empty name meaning auto
+func (d *Deserializers) Get(name string) (Deserializer, bool) {
+	if name == "" {
+		name = DeserializerAuto
+	}
+	deserializer, ok := d.byName[name]
+	return deserializer, ok
+}
+
+// ForTopic returns the key and value deserializers of the first mapping
+// whose pattern matches the topic, auto when none does
+func (d *Deserializers) ForTopic(topic string) (key, value Deserializer) {
+	key, _ = d.Get(DeserializerAuto)
+	value = key
+	for _, mapping := range d.mappings {
+		if ok, _ := path.Match(mapping.Topic, topic); ok {
+			key, _ = d.Get(mapping.Key)
+			value, _ = d.Get(mapping.Value)
+			break
+		}
+	}
+	return key, value
+}
+
+// DecodeMessage decodes a message with the deserializers of its topic
+func (d *Deserializers) DecodeMessage(msg *models.Message) {
+	key, value := d.ForTopic(msg.Topic)
+	Decode(msg, key, value)
+}
+
+// Decode fills the decoded key and value of a message, replacing previous
+// decodes. Missing keys and values are left empty.
+func Decode(msg *models.Message, key, value Deserializer) {
+	msg.DecodedKey, msg.DecodedValue = nil, nil
+
+	var errs []error
+	if msg.Key != nil {
+		if text, err := key.Deserialize(msg.Topic, KeyField, msg.Key); err == nil {
+			msg.DecodedKey = &text
+		} else if !errors.Is(err, ErrSkipped) {
+			errs = append(errs, fmt.Errorf("key: %w", err))
+		}
+	}
+	if msg.Value != nil {
+		if text, err := value.Deserialize(msg.Topic, ValueField, msg.Value); err == nil {
+			msg.DecodedValue = &text
+		} else if !errors.Is(err, ErrSkipped) {
+			errs = append(errs, fmt.Errorf("value: %w", err))
+		}
+	}
+	msg.DecodeErr = errors.Join(errs...)
+}
@@ -0,0 +1,291 @@
+// Package filter implements the message filter expressions used to narrow
+// down the messages displayed, searched or exported.
+//
+// An expression is made of comparisons combined with `&&`, `||`, `!` and
+// parentheses:
+//
+//	key == "order-42"
+//	value ~ "timeout"                    (case-insensitive substring)
+//	value =~ "^ERR-[0-9]+"               (regular expression, !~ negates)
+//	header.trace-id == "abc"
+//	value.order.status == "FAILED"       (JSON path, also key.<path>)
+//	value.items[0].qty >= 3
+//	offset >= 1000 && offset < 2000
+//	partition == 3
+//	timestamp > -6h                      (also RFC3339 or unix milliseconds)
+//
+// A bare word or quoted string matches messages whose key or value contains it.
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/clemsau/kafe/internal/models"
+)
+
+// Filter is a compiled filter expression
+type Filter struct {
+	source string
+	root   node
+}
+
+// Parse compiles a filter expression. An empty expression matches every message.
+func Parse(input string) (*Filter, error) {
+	return ParseAt(input, time.Now())
+}
+
+// ParseAt compiles a filter expression, resolving relative times against now
+func ParseAt(input string, now time.Time) (*Filter, error) {
+	f := &Filter{source: strings.TrimSpace(input)}
+	if f.source == "" {
+		return f, nil
+	}
+
+	tokens, err := tokenize(f.source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, now: now}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	f.root = root
+	return f, nil
+}
+
+// String returns the source expression
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.source
+}
+
+// IsEmpty reports whether the filter matches every message
+func (f *Filter) IsEmpty() bool {
+	return f == nil || f.root == nil
+}
+
+// Match reports whether the message satisfies the filter
+func (f *Filter) Match(msg *models.Message) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	return f.root.eval(&record{msg: msg})
+}
+
+// record wraps a message being evaluated and caches its decoded JSON
+type record struct {
+	msg         *models.Message
+	key, value  any
+	keyParsed   bool
+	valueParsed bool
+}
+
+func (r *record) json(kind fieldKind) any {
+	if kind == fieldKeyPath {
+		if !r.keyParsed {
+			r.key, r.keyParsed = decodeJSON(r.msg.KeyText()), true
+		}
+		return r.key
+	}
+	if !r.valueParsed {
+		r.value, r.valueParsed = decodeJSON(r.msg.ValueText()), true
+	}
+	return r.value
+}
+
+func decodeJSON(text string) any {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil
+	}
+	return v
+}
+
+type node interface {
+	eval(r *record) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(r *record) bool { return n.left.eval(r) && n.right.eval(r) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(r *record) bool { return n.left.eval(r) || n.right.eval(r) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(r *record) bool { return !n.inner.eval(r) }
+
+// textNode matches a lowercase term against the key and value
+type textNode struct{ term string }
+
+func (n textNode) eval(r *record) bool {
+	return strings.Contains(strings.ToLower(r.msg.KeyText()), n.term) ||
+		strings.Contains(strings.ToLower(r.msg.ValueText()), n.term)
+}
+
+type fieldKind int
+
+const (
+	fieldKey fieldKind = iota
+	fieldValue
+	fieldHeader
+	fieldKeyPath
+	fieldValuePath
+	fieldPartition
+	fieldOffset
+	fieldTimestamp
+)
+
+type pathSegment struct {
+	key   string
+	index int
+}
+
+type field struct {
+	kind fieldKind
+	name string
+	path []pathSegment
+}
+
+type comparison struct {
+	field   field
+	op      string
+	literal string
+	number  float64
+	numeric bool
+	time    time.Time
+	regex   *regexp.Regexp
+}
+
+func (c comparison) eval(r *record) bool {
+	switch c.field.kind {
+	case fieldPartition:
+		return compareNumbers(float64(r.msg.Partition), c.op, c.number)
+	case fieldOffset:
+		return compareNumbers(float64(r.msg.Offset), c.op, c.number)
+	case fieldTimestamp:
+		return compareNumbers(float64(r.msg.Timestamp.UnixMilli()), c.op, float64(c.time.UnixMilli()))
+	case fieldKey:
+		return c.compareText(r.msg.KeyText())
+	case fieldValue:
+		return c.compareText(r.msg.ValueText())
+	case fieldHeader:
+		value, ok := r.msg.Header(c.field.name)
+		if !ok {
+			return false
+		}
+		return c.compareText(value)
+	case fieldKeyPath, fieldValuePath:
+		value, ok := lookup(r.json(c.field.kind), c.field.path)
+		if !ok {
+			return false
+		}
+		if number, isNumber := value.(json.Number); isNumber && c.numeric {
+			if n, err := number.Float64(); err == nil {
+				return compareNumbers(n, c.op, c.number)
+			}
+		}
+		if c.op == "==" || c.op == "!=" || isTextOp(c.op) {
+			return c.compareText(jsonText(value))
+		}
+	}
+	return false
+}
+
+func (c comparison) compareText(text string) bool {
+	switch c.op {
+	case "==":
+		return text == c.literal
+	case "!=":
+		return text != c.literal
+	case "~":
+		return strings.Contains(strings.ToLower(text), strings.ToLower(c.literal))
+	case "=~":
+		return c.regex.MatchString(text)
+	case "!~":
+		return !c.regex.MatchString(text)
+	}
+	return false
+}
+
+func compareNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// lookup walks a decoded JSON document along the given path
+func lookup(doc any, path []pathSegment) (any, bool) {
+	current := doc
+	for _, segment := range path {
+		if segment.index >= 0 {
+			list, ok := current.([]any)
+			if !ok || segment.index >= len(list) {
+				return nil, false
+			}
+			current = list[segment.index]
+			continue
+		}
+
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[segment.key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// jsonText renders a decoded JSON value the way users would write it in a filter
+func jsonText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprintf("%t", v)
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v); err != nil {
+			return ""
+		}
+		return strings.TrimSpace(buf.String())
+	}
+}
@@ -0,0 +1,159 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clemsau/kafe/internal/models"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func testMessage() *models.Message {
+	return &models.Message{
+		Partition: 2,
+		Offset:    1500,
+		Timestamp: now.Add(-time.Hour),
+		Key:       []byte(`{"id": "order-42", "region": "eu"}`),
+		Value:     []byte(`{"order": {"status": "FAILED", "total": 12.5}, "items": [{"sku": "A-1", "qty": 3}], "note": "Request Timeout"}`),
+		Headers:   []models.MessageHeader{{Key: "trace-id", Value: []byte("abc-123")}},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// free text and substrings, case-insensitive
+		{`timeout`, true},
+		{`"request timeout"`, true},
+		{`missing`, false},
+		{`value ~ "TIMEOUT"`, true},
+		{`key contains "ORDER-42"`, true},
+		{`key == "order-42"`, false},
+		// regular expressions
+		{`value =~ "Request T[a-z]+"`, true},
+		{`value matches "^ERR"`, false},
+		{`value !~ "^ERR"`, true},
+		// headers
+		{`header.trace-id == "abc-123"`, true},
+		{`headers.trace-id ~ "ABC"`, true},
+		{`header.trace-id != "abc-123"`, false},
+		{`header.missing == "x"`, false},
+		// JSON paths
+		{`value.order.status == "FAILED"`, true},
+		{`value.order.total > 10`, true},
+		{`value.order.total <= 12`, false},
+		{`value.items[0].sku == "A-1"`, true},
+		{`value.items[0].qty >= 3`, true},
+		{`value.items[1].qty >= 3`, false},
+		{`key.region == eu`, true},
+		{`value.order == '{"status":"FAILED","total":12.5}'`, true},
+		// offset, partition and timestamp ranges
+		{`offset >= 1000 && offset < 2000`, true},
+		{`offset > 1500`, false},
+		{`partition == 2`, true},
+		{`partition != 2`, false},
+		{`timestamp > -2h`, true},
+		{`timestamp > -30m`, false},
+		{`ts >= "2024-05-01T10:00:00Z" && ts < "2024-05-01T12:00:00Z"`, true},
+		{`timestamp < 1714564800000`, true},
+		// boolean combinations
+		{`missing || partition == 2`, true},
+		{`!missing`, true},
+		{`not (timeout or missing)`, false},
+		{`timeout partition == 3`, false},
+		{``, true},
+	}
+
+	msg := testMessage()
+	for _, test := range tests {
+		f, err := ParseAt(test.expr, now)
+		if err != nil {
+			t.Errorf("ParseAt(%q): %v", test.expr, err)
+			continue
+		}
+		if got := f.Match(msg); got != test.want {
+			t.Errorf("%q matches %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// && binds tighter than ||
+		{`partition == 2 || partition == 3 && offset == 0`, true},
+		{`(partition == 2 || partition == 3) && offset == 0`, false},
+		// ! binds tighter than &&
+		{`!partition == 3 && offset == 1500`, true},
+		{`!(partition == 2 && offset == 1500)`, false},
+		// implicit and is an and
+		{`partition == 2 offset == 0 || timeout`, true},
+		{`partition == 2 (offset == 0 || timeout)`, true},
+	}
+
+	msg := testMessage()
+	for _, test := range tests {
+		f, err := ParseAt(test.expr, now)
+		if err != nil {
+			t.Errorf("ParseAt(%q): %v", test.expr, err)
+			continue
+		}
+		if got := f.Match(msg); got != test.want {
+			t.Errorf("%q matches %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`(partition == 2`, `expected ')' at position 15`},
+		{`partition == 2)`, `unexpected ")" at position 14`},
+		{`key ==`, `expected a value after "==" at position 6`},
+		{`a &&`, `unexpected end of expression`},
+		{`a & b`, `unexpected '&' at position 2`},
+		{`== x`, `unexpected "==" at position 0`},
+		{`partition ~ 2`, `operator ~ is not supported for partition`},
+		{`offset == abc`, `offset expects an integer`},
+		{`timestamp > yesterday`, `invalid time "yesterday"`},
+		{`color == red`, `unknown field "color"`},
+		{`header == x`, `header needs a name`},
+		{`value =~ "("`, `invalid regular expression`},
+		{`value.items[x] == 1`, `invalid index in path`},
+	}
+
+	for _, test := range tests {
+		_, err := ParseAt(test.expr, now)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ParseAt(%q) error = %v, want %q", test.expr, err, test.want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"now", now},
+		{"-90m", now.Add(-90 * time.Minute)},
+		{"1714564800000", time.UnixMilli(1714564800000)},
+		{"2024-05-01T10:00:00Z", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, test := range tests {
+		got, err := ParseTime(test.text, now)
+		if err != nil || !got.Equal(test.want) {
+			t.Errorf("ParseTime(%q) = %v, %v, want %v", test.text, got, err, test.want)
+		}
+	}
+}
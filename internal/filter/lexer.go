@@ -0,0 +1,141 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators sorted so that longer operators are matched first
+var operators = []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "~", "=", "!"}
+
+// isSpecial reports whether r terminates a bare word
+func isSpecial(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`()"'=!<>~&|`, r)
+}
+
+// tokenize splits a filter expression into tokens
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			text, next, err := readString(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: i})
+			i = next
+		default:
+			if op := matchOperator(runes[i:]); op != "" {
+				tokens = append(tokens, operatorToken(op, i))
+				i += len(op)
+				continue
+			}
+
+			start := i
+			for i < len(runes) && !isSpecial(runes[i]) {
+				i++
+			}
+			if i == start {
+				// a special rune starting no operator, e.g. a lone & or |
+				return nil, fmt.Errorf("unexpected %q at position %d", r, start)
+			}
+			word := string(runes[start:i])
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, token{kind: tokAnd, text: word, pos: start})
+			case "or":
+				tokens = append(tokens, token{kind: tokOr, text: word, pos: start})
+			case "not":
+				tokens = append(tokens, token{kind: tokNot, text: word, pos: start})
+			case "contains":
+				tokens = append(tokens, token{kind: tokOp, text: "~", pos: start})
+			case "matches":
+				tokens = append(tokens, token{kind: tokOp, text: "=~", pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokWord, text: word, pos: start})
+			}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+func matchOperator(runes []rune) string {
+	for _, op := range operators {
+		if strings.HasPrefix(string(runes[:min(len(runes), len(op))]), op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func operatorToken(op string, pos int) token {
+	switch op {
+	case "&&":
+		return token{kind: tokAnd, text: op, pos: pos}
+	case "||":
+		return token{kind: tokOr, text: op, pos: pos}
+	case "!":
+		return token{kind: tokNot, text: op, pos: pos}
+	case "=":
+		return token{kind: tokOp, text: "==", pos: pos}
+	default:
+		return token{kind: tokOp, text: op, pos: pos}
+	}
+}
+
+// readString reads a quoted string starting at runes[start], handling backslash escapes
+func readString(runes []rune, start int) (string, int, error) {
+	quote := runes[start]
+	var sb strings.Builder
+
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				sb.WriteRune(runes[i])
+			}
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteRune(runes[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string at position %d", start)
+}
@@ -0,0 +1,61 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []token
+	}{
+		{`key == "a b"`, []token{{tokWord, "key", 0}, {tokOp, "==", 4}, {tokString, "a b", 7}}},
+		{`value=~'^x\'y'`, []token{{tokWord, "value", 0}, {tokOp, "=~", 5}, {tokString, "^x'y", 7}}},
+		{`a && b || !c`, []token{{tokWord, "a", 0}, {tokAnd, "&&", 2}, {tokWord, "b", 5}, {tokOr, "||", 7}, {tokNot, "!", 10}, {tokWord, "c", 11}}},
+		{`a AND b or not c`, []token{{tokWord, "a", 0}, {tokAnd, "AND", 2}, {tokWord, "b", 6}, {tokOr, "or", 8}, {tokNot, "not", 11}, {tokWord, "c", 15}}},
+		{`value contains x`, []token{{tokWord, "value", 0}, {tokOp, "~", 6}, {tokWord, "x", 15}}},
+		{`key matches x`, []token{{tokWord, "key", 0}, {tokOp, "=~", 4}, {tokWord, "x", 12}}},
+		{`offset>=1 (partition<2)`, []token{{tokWord, "offset", 0}, {tokOp, ">=", 6}, {tokWord, "1", 8}, {tokLParen, "(", 10}, {tokWord, "partition", 11}, {tokOp, "<", 20}, {tokWord, "2", 21}, {tokRParen, ")", 22}}},
+		{`key = x`, []token{{tokWord, "key", 0}, {tokOp, "==", 4}, {tokWord, "x", 6}}},
+		{`value !~ x`, []token{{tokWord, "value", 0}, {tokOp, "!~", 6}, {tokWord, "x", 9}}},
+	}
+
+	for _, test := range tests {
+		tokens, err := tokenize(test.input)
+		if err != nil {
+			t.Errorf("tokenize(%q): %v", test.input, err)
+			continue
+		}
+		want := append(test.want, token{kind: tokEOF, pos: len([]rune(test.input))})
+		if len(tokens) != len(want) {
+			t.Errorf("tokenize(%q) = %v, want %v", test.input, tokens, want)
+			continue
+		}
+		for i := range want {
+			if tokens[i] != want[i] {
+				t.Errorf("tokenize(%q) token %d = %v, want %v", test.input, i, tokens[i], want[i])
+			}
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`a & b`, `unexpected '&' at position 2`},
+		{`a | b`, `unexpected '|' at position 2`},
+		{`&`, `unexpected '&' at position 0`},
+		{`key == "open`, `unterminated string at position 7`},
+		{`value ~ 'x`, `unterminated string at position 8`},
+	}
+
+	for _, test := range tests {
+		_, err := tokenize(test.input)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("tokenize(%q) error = %v, want %q", test.input, err, test.want)
+		}
+	}
+}
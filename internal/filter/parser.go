@@ -0,0 +1,263 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+	now    time.Time
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr parses: and { ("||" | "or") and }
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: unary { ("&&" | "and" | implicit) unary }
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokString, tokLParen, tokNot:
+			// Juxtaposed terms are AND-ed, e.g. `timeout partition == 2`
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+// parseUnary parses: ("!" | "not") unary | primary
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a parenthesized expression, a comparison or a free-text term
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("expected ')' at position %d", closing.pos)
+		}
+		return inner, nil
+	case tokString:
+		return textNode{strings.ToLower(tok.text)}, nil
+	case tokWord:
+		if p.peek().kind == tokOp {
+			return p.parseComparison(tok)
+		}
+		return textNode{strings.ToLower(tok.text)}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+}
+
+// parseComparison parses: field op literal
+func (p *parser) parseComparison(fieldTok token) (node, error) {
+	op := p.next().text
+	lit := p.next()
+	if lit.kind != tokWord && lit.kind != tokString {
+		return nil, fmt.Errorf("expected a value after %q at position %d", op, lit.pos)
+	}
+
+	f, err := parseField(fieldTok.text)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := comparison{field: f, op: op, literal: lit.text}
+
+	switch f.kind {
+	case fieldPartition, fieldOffset:
+		if !isOrderingOp(op) {
+			return nil, fmt.Errorf("operator %s is not supported for %s", op, fieldTok.text)
+		}
+		n, err := strconv.ParseInt(lit.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects an integer, got %q", fieldTok.text, lit.text)
+		}
+		cmp.number = float64(n)
+	case fieldTimestamp:
+		if !isOrderingOp(op) {
+			return nil, fmt.Errorf("operator %s is not supported for %s", op, fieldTok.text)
+		}
+		ts, err := ParseTime(lit.text, p.now)
+		if err != nil {
+			return nil, err
+		}
+		cmp.time = ts
+	case fieldKey, fieldValue, fieldHeader:
+		if op != "==" && op != "!=" && !isTextOp(op) {
+			return nil, fmt.Errorf("operator %s is not supported for %s", op, fieldTok.text)
+		}
+	case fieldKeyPath, fieldValuePath:
+		if n, err := strconv.ParseFloat(lit.text, 64); err == nil {
+			cmp.number = n
+			cmp.numeric = true
+		}
+	}
+
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile(lit.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", lit.text, err)
+		}
+		cmp.regex = re
+	}
+
+	return cmp, nil
+}
+
+func isOrderingOp(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+func isTextOp(op string) bool {
+	return op == "~" || op == "=~" || op == "!~"
+}
+
+// parseField resolves a field reference such as `key`, `header.trace-id` or `value.order.status`
+func parseField(name string) (field, error) {
+	head, rest, hasRest := strings.Cut(name, ".")
+	switch strings.ToLower(head) {
+	case "key":
+		if hasRest {
+			path, err := parsePath(rest)
+			return field{kind: fieldKeyPath, path: path}, err
+		}
+		return field{kind: fieldKey}, nil
+	case "value":
+		if hasRest {
+			path, err := parsePath(rest)
+			return field{kind: fieldValuePath, path: path}, err
+		}
+		return field{kind: fieldValue}, nil
+	case "header", "headers":
+		if !hasRest || rest == "" {
+			return field{}, fmt.Errorf("%s needs a name, e.g. %s.trace-id", head, head)
+		}
+		return field{kind: fieldHeader, name: rest}, nil
+	case "partition":
+		return field{kind: fieldPartition}, nil
+	case "offset":
+		return field{kind: fieldOffset}, nil
+	case "timestamp", "ts":
+		return field{kind: fieldTimestamp}, nil
+	}
+	return field{}, fmt.Errorf("unknown field %q", name)
+}
+
+// parsePath splits a JSON path such as `items[0].sku` into segments
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		var indexes []int
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+			for _, idx := range strings.Split(strings.TrimSuffix(part[i+1:], "]"), "][") {
+				n, err := strconv.Atoi(idx)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index in path %q", path)
+				}
+				indexes = append(indexes, n)
+			}
+		}
+
+		if name != "" {
+			segments = append(segments, pathSegment{key: name, index: -1})
+		}
+		for _, n := range indexes {
+			segments = append(segments, pathSegment{index: n})
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return segments, nil
+}
+
+// ParseTime parses an absolute or relative point in time. Accepted forms are
+// RFC3339, `2006-01-02 15:04:05`, `2006-01-02`, unix milliseconds, `now` and
+// a negative duration relative to now such as `-6h`.
+func ParseTime(text string, now time.Time) (time.Time, error) {
+	if strings.EqualFold(text, "now") {
+		return now, nil
+	}
+	if strings.HasPrefix(text, "-") {
+		if d, err := time.ParseDuration(text[1:]); err == nil {
+			return now.Add(-d), nil
+		}
+	}
+	if ms, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if ts, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", text)
+}
@@ -0,0 +1,255 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/clemsau/kafe/internal/models"
+)
+
+const (
+	// batchFetchVersion is the first fetch version returning the last
+	// stable offset and the aborted transactions
+	batchFetchVersion = 4
+	// batchFetchMaxWait bounds how long the broker waits for data
+	batchFetchMaxWait = 500
+	// recordBatchHeaderSize is the size of a record batch header, including
+	// its base offset and length
+	recordBatchHeaderSize = 61
+)
+
+// Control record types, from the key of control records
+const (
+	ControlAbort  = "ABORT"
+	ControlCommit = "COMMIT"
+)
+
+// RecordBatch describes a record batch as stored by the broker
+type RecordBatch struct {
+	Partition   int32
+	FirstOffset int64
+	LastOffset  int64
+	Version     int8
+	Codec       string
+	// Size is the uncompressed size of the batch, header included
+	Size           int
+	ProducerID     int64
+	ProducerEpoch  int16
+	BaseSequence   int32
+	Transactional  bool
+	Control        bool
+	LogAppendTime  bool
+	FirstTimestamp time.Time
+	MaxTimestamp   time.Time
+	// Aborted is set on the data batches of aborted transactions
+	Aborted bool
+	// ControlType is the type of the marker of a control batch
+	ControlType string
+	Records     []models.Message
+}
+
+// BatchPage is the result of a batch fetch
+type BatchPage struct {
+	Batches          []RecordBatch
+	HighWaterMark    int64
+	LastStableOffset int64
+	LogStartOffset   int64
+	// Next is the offset to fetch the following batches from
+	Next int64
+}
+
+// FetchBatches fetches the record batches of a partition from offset, up to
+// maxBytes and the high watermark, so open transactions are included.
+// Aborted transactions and control batches are kept and flagged.
+func (c *Client) FetchBatches(topic string, partition int32, offset int64, maxBytes int32) (*BatchPage, error) {
+	broker, err := c.Leader(topic, partition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the leader of partition %d: %w", partition, err)
+	}
+
+	block, err := fetchBlock(broker, topic, partition, offset, maxBytes, sarama.ReadUncommitted)
+	if err != nil {
+		return nil, err
+	}
+	// aborted transactions are only returned to read committed fetches,
+	// which stop at the last stable offset
+	committed, err := fetchBlock(broker, topic, partition, offset, maxBytes, sarama.ReadCommitted)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &BatchPage{
+		HighWaterMark:    block.HighWaterMarkOffset,
+		LastStableOffset: block.LastStableOffset,
+		LogStartOffset:   block.LogStartOffset,
+		Next:             offset,
+	}
+
+	aborted := newAbortTracker(committed.AbortedTransactions)
+	for _, records := range block.RecordsSet {
+		if records.RecordBatch == nil || records.RecordBatch.PartialTrailingRecord {
+			continue
+		}
+		batch := newRecordBatch(topic, partition, records.RecordBatch)
+		aborted.mark(&batch)
+		if batch.LastOffset < offset {
+			continue
+		}
+		page.Batches = append(page.Batches, batch)
+		page.Next = batch.LastOffset + 1
+	}
+	return page, nil
+}
+
+// fetchBlock fetches a partition from offset with the isolation level
+func fetchBlock(broker *sarama.Broker, topic string, partition int32, offset int64, maxBytes int32, isolation sarama.IsolationLevel) (*sarama.FetchResponseBlock, error) {
+	request := &sarama.FetchRequest{
+		Version:     batchFetchVersion,
+		MaxWaitTime: batchFetchMaxWait,
+		MinBytes:    1,
+		MaxBytes:    maxBytes,
+		Isolation:   isolation,
+	}
+	request.AddBlock(topic, partition, offset, maxBytes, -1)
+
+	response, err := broker.Fetch(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %w", err)
+	}
+	block := response.GetBlock(topic, partition)
+	if block == nil {
+		return nil, fmt.Errorf("no data returned for partition %d", partition)
+	}
+	if block.Err != sarama.ErrNoError {
+		return nil, fmt.Errorf("fetch failed: %w", block.Err)
+	}
+	return block, nil
+}
+
+func newRecordBatch(topic string, partition int32, rb *sarama.RecordBatch) RecordBatch {
+	batch := RecordBatch{
+		Partition:      partition,
+		FirstOffset:    rb.FirstOffset,
+		LastOffset:     rb.LastOffset(),
+		Version:        rb.Version,
+		Codec:          rb.Codec.String(),
+		Size:           recordBatchHeaderSize,
+		ProducerID:     rb.ProducerID,
+		ProducerEpoch:  rb.ProducerEpoch,
+		BaseSequence:   rb.FirstSequence,
+		Transactional:  rb.IsTransactional,
+		Control:        rb.Control,
+		LogAppendTime:  rb.LogAppendTime,
+		FirstTimestamp: rb.FirstTimestamp,
+		MaxTimestamp:   rb.MaxTimestamp,
+	}
+
+	for _, record := range rb.Records {
+		batch.Size += recordSize(record)
+
+		timestamp := rb.FirstTimestamp.Add(record.TimestampDelta)
+		if rb.LogAppendTime {
+			timestamp = rb.MaxTimestamp
+		}
+		msg := models.Message{
+			Topic:     topic,
+			Partition: partition,
+			Offset:    rb.FirstOffset + record.OffsetDelta,
+			Timestamp: timestamp,
+			Key:       record.Key,
+			Value:     record.Value,
+		}
+		for _, header := range record.Headers {
+			msg.Headers = append(msg.Headers, models.MessageHeader{
+				Key:   string(header.Key),
+				Value: header.Value,
+			})
+		}
+		batch.Records = append(batch.Records, msg)
+	}
+
+	if batch.Control && len(rb.Records) > 0 {
+		batch.ControlType = controlType(rb.Records[0].Key)
+	}
+	return batch
+}
+
+// controlType reads the type of a control record from its key: a version
+// followed by the type, both int16
+func controlType(key []byte) string {
+	if len(key) < 4 {
+		return "UNKNOWN"
+	}
+	switch binary.BigEndian.Uint16(key[2:4]) {
+	case 0:
+		return ControlAbort
+	case 1:
+		return ControlCommit
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", binary.BigEndian.Uint16(key[2:4]))
+}
+
+// recordSize returns the encoded size of a record in a batch
+func recordSize(record *sarama.Record) int {
+	body := 1 + // attributes
+		varintSize(int64(record.TimestampDelta/time.Millisecond)) +
+		varintSize(record.OffsetDelta) +
+		bytesSize(record.Key) +
+		bytesSize(record.Value) +
+		varintSize(int64(len(record.Headers)))
+	for _, header := range record.Headers {
+		body += bytesSize(header.Key) + bytesSize(header.Value)
+	}
+	return varintSize(int64(body)) + body
+}
+
+// bytesSize returns the encoded size of nullable bytes in a record
+func bytesSize(data []byte) int {
+	if data == nil {
+		return varintSize(-1)
+	}
+	return varintSize(int64(len(data))) + len(data)
+}
+
+// varintSize returns the size of a zigzag encoded varint
+func varintSize(v int64) int {
+	return len(binary.AppendVarint(nil, v))
+}
+
+// abortTracker follows the aborted transactions of a fetch, as consumers
+// do to skip aborted records: a producer's transaction is aborted from its
+// first offset until the producer's abort marker
+type abortTracker struct {
+	pending []*sarama.AbortedTransaction
+	open    map[int64]bool
+}
+
+func newAbortTracker(transactions []*sarama.AbortedTransaction) *abortTracker {
+	pending := append([]*sarama.AbortedTransaction(nil), transactions...)
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].FirstOffset < pending[j].FirstOffset
+	})
+	return &abortTracker{pending: pending, open: make(map[int64]bool)}
+}
+
+// mark flags the batch as aborted when it belongs to an aborted transaction
+func (t *abortTracker) mark(batch *RecordBatch) {
+	for len(t.pending) > 0 && t.pending[0].FirstOffset <= batch.LastOffset {
+		t.open[t.pending[0].ProducerID] = true
+		t.pending = t.pending[1:]
+	}
+
+	if !batch.Transactional || !t.open[batch.ProducerID] {
+		return
+	}
+	if batch.Control {
+		if batch.ControlType == ControlAbort {
+			delete(t.open, batch.ProducerID)
+		}
+		return
+	}
+	batch.Aborted = true
+}
@@ -0,0 +1,226 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/clemsau/kafe/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	// bulkBatchSize is the number of records sent, and committed when
+	// transactional, at once
+	bulkBatchSize = 500
+	// maxReportedFailures caps the failed records kept for the summary
+	maxReportedFailures = 100
+)
+
+// BulkRecord is a record read from a file to be produced
+type BulkRecord struct {
+	Line      int
+	Message   models.Message
+	Partition int32
+}
+
+// BulkOptions configures a bulk produce run
+type BulkOptions struct {
+	ProducerOptions
+	// Rate caps the records sent per second, 0 means unlimited
+	Rate float64
+}
+
+// RecordFailure describes a record that could not be delivered
+type RecordFailure struct {
+	Line int
+	Err  error
+}
+
+// BulkResult summarizes a bulk produce run
+type BulkResult struct {
+	Delivered int
+	Failed    int
+	// Reasons counts failed records by error message
+	Reasons  map[string]int
+	Failures []RecordFailure
+	Elapsed  time.Duration
+}
+
+func (r *BulkResult) fail(line int, err error) {
+	r.Failed++
+	r.Reasons[err.Error()]++
+	if len(r.Failures) < maxReportedFailures {
+		r.Failures = append(r.Failures, RecordFailure{Line: line, Err: err})
+	}
+}
+
+// SortedReasons returns the failure reasons, most frequent first
+func (r BulkResult) SortedReasons() []string {
+	reasons := make([]string, 0, len(r.Reasons))
+	for reason := range r.Reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if r.Reasons[reasons[i]] != r.Reasons[reasons[j]] {
+			return r.Reasons[reasons[i]] > r.Reasons[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	return reasons
+}
+
+// ProduceBulk produces every record returned by next to the topic until
+// next returns io.EOF. Records next fails to decode are counted as failed
+// when the error wraps a line number through RecordError. progress, when
+// set, is called after each batch with the running totals.
+func (c *Client) ProduceBulk(ctx context.Context, topic string, next func() (BulkRecord, error), opts BulkOptions, progress func(BulkResult)) (BulkResult, error) {
+	result := BulkResult{Reasons: make(map[string]int)}
+	started := time.Now()
+
+	producer, err := c.NewProducer(opts.ProducerOptions)
+	if err != nil {
+		return result, err
+	}
+	defer producer.Close()
+
+	batchSize := bulkBatchSize
+	if opts.Rate > 0 {
+		// Send about ten batches per second to keep the rate smooth
+		batchSize = max(1, min(bulkBatchSize, int(opts.Rate/10)))
+	}
+
+	sent := 0
+	batch := make([]*sarama.ProducerMessage, 0, batchSize)
+	for done := false; !done; {
+		batch = batch[:0]
+		for len(batch) < batchSize {
+			record, err := next()
+			if errors.Is(err, io.EOF) {
+				done = true
+				break
+			}
+			if err != nil {
+				var recordErr *RecordError
+				if !errors.As(err, &recordErr) {
+					return result, err
+				}
+				result.fail(recordErr.Line, recordErr.Err)
+				continue
+			}
+
+			record.Message.Topic = topic
+			msg := newProducerMessage(record.Message, record.Partition)
+			msg.Metadata = recordMetadata{partition: record.Partition, line: record.Line}
+			batch = append(batch, msg)
+		}
+
+		if len(batch) == 0 {
+			continue
+		}
+
+		if opts.Rate > 0 {
+			wait := time.Until(started.Add(time.Duration(float64(sent) / opts.Rate * float64(time.Second))))
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				result.Elapsed = time.Since(started)
+				return result, ctx.Err()
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			result.Elapsed = time.Since(started)
+			return result, err
+		}
+
+		sendBatch(producer, batch, &result)
+		sent += len(batch)
+
+		if progress != nil {
+			result.Elapsed = time.Since(started)
+			progress(result)
+		}
+	}
+
+	result.Elapsed = time.Since(started)
+	return result, nil
+}
+
+// sendBatch sends the batch, inside its own transaction for transactional
+// producers, and accounts for delivered and failed records
+func sendBatch(producer *Producer, batch []*sarama.ProducerMessage, result *BulkResult) {
+	failAll := func(err error) {
+		for _, msg := range batch {
+			result.fail(msg.Metadata.(recordMetadata).line, err)
+		}
+	}
+
+	transactional := producer.IsTransactional()
+	if transactional {
+		if err := producer.BeginTxn(); err != nil {
+			failAll(fmt.Errorf("begin transaction: %w", err))
+			return
+		}
+	}
+
+	err := producer.SendMessages(batch)
+
+	if transactional {
+		if err != nil {
+			reason := firstError(err)
+			if abortErr := producer.AbortTxn(); abortErr != nil {
+				reason = fmt.Errorf("%v (abort transaction: %v)", reason, abortErr)
+			}
+			failAll(fmt.Errorf("transaction aborted: %w", reason))
+			return
+		}
+		if err := producer.CommitTxn(); err != nil {
+			failAll(fmt.Errorf("commit transaction: %w", err))
+			return
+		}
+		result.Delivered += len(batch)
+		return
+	}
+
+	failed := 0
+	var producerErrors sarama.ProducerErrors
+	switch {
+	case errors.As(err, &producerErrors):
+		for _, pe := range producerErrors {
+			result.fail(pe.Msg.Metadata.(recordMetadata).line, pe.Err)
+			failed++
+		}
+	case err != nil:
+		failAll(err)
+		return
+	}
+	result.Delivered += len(batch) - failed
+}
+
+// firstError unwraps sarama.ProducerErrors to its first error, which is
+// more readable than the aggregated message
+func firstError(err error) error {
+	var producerErrors sarama.ProducerErrors
+	if errors.As(err, &producerErrors) && len(producerErrors) > 0 {
+		return producerErrors[0].Err
+	}
+	return err
+}
+
+// RecordError reports a record that could not be read from its source
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
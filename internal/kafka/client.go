@@ -12,6 +12,7 @@ import (
 type Client struct {
 	sarama.Client
 	addresses []string
+	decoder   MessageDecoder
 }
 
 func NewClient(brokers []string, config *sarama.Config) (*Client, error) {
@@ -35,40 +36,85 @@ func (c *Client) GetAddresses() []string {
 	return c.addresses
 }
 
-func (c *Client) GetTopicInfo(topic string) (models.TopicInfo, error) {
-	partitions, err := c.Partitions(topic)
-	if err != nil {
-		return models.TopicInfo{}, err
+// MessageDecoder decodes the keys and values of consumed messages
+type MessageDecoder interface {
+	DecodeMessage(msg *models.Message)
+}
+
+// SetDecoder sets the decoder of the consumed messages
+func (c *Client) SetDecoder(decoder MessageDecoder) {
+	c.decoder = decoder
+}
+
+// Decoder returns the decoder of the consumed messages, nil if none was set
+func (c *Client) Decoder() MessageDecoder {
+	return c.decoder
+}
+
+// DecodeMessage decodes the key and value of a consumed message with the
+// client's decoder, if any
+func (c *Client) DecodeMessage(msg *models.Message) {
+	if c.decoder != nil {
+		c.decoder.DecodeMessage(msg)
 	}
+}
 
-	info := models.TopicInfo{
-		Name:       topic,
-		Partitions: len(partitions),
+// ListTopics returns the topics of the cluster, refreshing the metadata
+// first so topics deleted since the last refresh are not listed
+func (c *Client) ListTopics() ([]string, error) {
+	if err := c.RefreshMetadata(); err != nil {
+		return nil, err
 	}
+	return c.Topics()
+}
 
-	if len(partitions) > 0 {
-		replicas, err := c.Replicas(topic, partitions[0])
-		if err == nil {
-			info.Replicas = len(replicas)
-		}
+func (c *Client) GetTopicInfo(topic string) (models.TopicInfo, error) {
+	infos, err := c.GetTopicInfos([]string{topic})
+	if info, ok := infos[topic]; ok {
+		return info, nil
 	}
+	return models.TopicInfo{}, err
+}
 
-	for _, partition := range partitions {
-		oldest, err := c.GetOffset(topic, partition, sarama.OffsetOldest)
-		if err != nil {
-			continue
+// GetTopicInfos returns the information of the topics, fetching the offsets
+// of all their partitions at once. Topics whose partitions cannot be listed
+// are left out and reported in the returned error.
+func (c *Client) GetTopicInfos(topics []string) (map[string]models.TopicInfo, error) {
+	offsets, err := c.ListOffsets(topics)
+
+	infos := make(map[string]models.TopicInfo, len(offsets))
+	for topic, partitionOffsets := range offsets {
+		partitions := make([]int32, 0, len(partitionOffsets))
+		for partition := range partitionOffsets {
+			partitions = append(partitions, partition)
 		}
 
-		newest, err := c.GetOffset(topic, partition, sarama.OffsetNewest)
-		if err != nil {
-			continue
+		info := models.TopicInfo{
+			Name:       topic,
+			Partitions: len(partitions),
 		}
 
-		info.Messages += newest - oldest
-	}
+		if len(partitions) > 0 {
+			replicas, err := c.Replicas(topic, partitions[0])
+			if err == nil {
+				info.Replicas = len(replicas)
+			}
+		}
 
-	info.Status = c.getTopicHealth(topic, partitions)
-	return info, nil
+		for _, p := range partitionOffsets {
+			if p.Err != nil {
+				continue
+			}
+			info.Messages += p.Newest - p.Oldest
+			info.HighWatermark += p.Newest
+			info.LastStable += p.LastStable
+		}
+
+		info.UnderReplicated, info.MinInSync = c.replication(topic, partitions)
+		info.Status = c.getTopicHealth(topic, partitions)
+		infos[topic] = info
+	}
+	return infos, err
 }
 
 func (c *Client) GetConsumerGroups(topic string) ([]models.ConsumerGroupInfo, error) {
@@ -85,6 +131,9 @@ func (c *Client) GetConsumerGroups(topic string) ([]models.ConsumerGroupInfo, er
 	}
 
 	var result []models.ConsumerGroupInfo
+	// records are fetched to measure time lags up to a budget per call, the
+	// time lag of the other partitions is left to estimates
+	timeLagFetches := maxTimeLagFetches
 
 	for group := range resp.Groups {
 		greq := &sarama.DescribeGroupsRequest{
@@ -101,29 +150,17 @@ func (c *Client) GetConsumerGroups(topic string) ([]models.ConsumerGroupInfo, er
 
 		gdesc := gresp.Groups[0]
 
-		consuming := false
-		members := 0
-		for _, member := range gdesc.Members {
-			metadata, err := member.GetMemberMetadata()
-			if err != nil {
-				continue
-			}
-
-			for _, t := range metadata.Topics {
-				if t == topic {
-					consuming = true
-					members++
-					break
-				}
-			}
-		}
-
-		if !consuming {
+		members := topicMembers(gdesc, topic)
+		if members == 0 {
 			continue
 		}
 
-		var totalLag int64
+		var totalLag, committedSum int64
+		var timeLag time.Duration
+		timeLagKnown := true
+		var lags []models.PartitionLag
 		partitions := c.partitionsForTopic(topic)
+		now := time.Now()
 
 		for _, partition := range partitions {
 			offReq := &sarama.OffsetFetchRequest{
@@ -145,30 +182,141 @@ func (c *Client) GetConsumerGroups(topic string) ([]models.ConsumerGroupInfo, er
 			committed := block.Offset
 			newest, err := c.GetOffset(topic, partition, sarama.OffsetNewest)
 			if err == nil && committed != -1 { // -1 indicates no committed offset
-				totalLag += newest - committed
+				lag := models.PartitionLag{
+					Partition:    partition,
+					Committed:    committed,
+					Newest:       newest,
+					TimeLagKnown: true,
+				}
+				totalLag += lag.Lag()
+				committedSum += committed
+
+				switch {
+				case committed >= newest:
+				case timeLagFetches > 0:
+					timeLagFetches--
+					lag.TimeLag, lag.TimeLagKnown = c.timeLag(topic, partition, committed, now)
+				default:
+					lag.TimeLagKnown = false
+				}
+				timeLag = max(timeLag, lag.TimeLag)
+				timeLagKnown = timeLagKnown && lag.TimeLagKnown
+				lags = append(lags, lag)
 			}
 		}
 
 		status := "Active"
 		if gdesc.State == "Dead" {
 			status = "Dead"
-		} else if totalLag > 1000 {
-			status = "Lagging"
 		}
 
 		result = append(result, models.ConsumerGroupInfo{
-			ID:         group,
-			Topic:      topic,
-			Members:    members,
-			TotalLag:   totalLag,
-			Status:     status,
-			LastUpdate: time.Now(),
+			ID:           group,
+			Topic:        topic,
+			Members:      members,
+			TotalLag:     totalLag,
+			Committed:    committedSum,
+			Partitions:   lags,
+			TimeLag:      timeLag,
+			TimeLagKnown: timeLagKnown,
+			Status:       status,
+			LastUpdate:   time.Now(),
 		})
 	}
 
 	return result, nil
 }
 
+// RetainedGroups returns the groups of ids that may still belong to the
+// topic: groups listed on the cluster that have members consuming it or
+// committed offsets on it. Empty groups keep their committed offsets, and
+// groups that cannot be described or whose offsets cannot be fetched are
+// retained as well.
+func (c *Client) RetainedGroups(topic string, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	coordinator, err := c.Coordinator("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coordinator: %w", err)
+	}
+	defer coordinator.Close()
+
+	listResp, err := coordinator.ListGroups(&sarama.ListGroupsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	var listed []string
+	for _, id := range ids {
+		if _, ok := listResp.Groups[id]; ok {
+			listed = append(listed, id)
+		}
+	}
+	if len(listed) == 0 {
+		return nil, nil
+	}
+
+	described := make(map[string]*sarama.GroupDescription)
+	if resp, err := coordinator.DescribeGroups(&sarama.DescribeGroupsRequest{Groups: listed}); err == nil {
+		for _, desc := range resp.Groups {
+			if desc.Err == sarama.ErrNoError {
+				described[desc.GroupId] = desc
+			}
+		}
+	}
+
+	partitions := c.partitionsForTopic(topic)
+	var retained []string
+	for _, id := range listed {
+		desc, ok := described[id]
+		if !ok || topicMembers(desc, topic) > 0 {
+			retained = append(retained, id)
+			continue
+		}
+
+		req := &sarama.OffsetFetchRequest{
+			Version:       1,
+			ConsumerGroup: id,
+		}
+		for _, partition := range partitions {
+			req.AddPartition(topic, partition)
+		}
+		resp, err := coordinator.FetchOffset(req)
+		if err != nil {
+			retained = append(retained, id)
+			continue
+		}
+		for _, partition := range partitions {
+			if block := resp.GetBlock(topic, partition); block == nil || block.Err != sarama.ErrNoError || block.Offset != -1 {
+				retained = append(retained, id)
+				break
+			}
+		}
+	}
+	return retained, nil
+}
+
+// topicMembers counts the members of a group consuming the topic
+func topicMembers(desc *sarama.GroupDescription, topic string) int {
+	members := 0
+	for _, member := range desc.Members {
+		metadata, err := member.GetMemberMetadata()
+		if err != nil {
+			continue
+		}
+
+		for _, t := range metadata.Topics {
+			if t == topic {
+				members++
+				break
+			}
+		}
+	}
+	return members
+}
+
 func (c *Client) getTopicHealth(topic string, partitions []int32) string {
 	for _, partition := range partitions {
 		leader, err := c.Leader(topic, partition)
@@ -184,6 +332,30 @@ func (c *Client) getTopicHealth(topic string, partitions []int32) string {
 	return "Ready"
 }
 
+// replication returns the number of partitions with fewer in-sync replicas
+// than replicas, and the fewest in-sync replicas of a partition, -1 when
+// unknown
+func (c *Client) replication(topic string, partitions []int32) (underReplicated, minInSync int) {
+	minInSync = -1
+	for _, partition := range partitions {
+		replicas, err := c.Replicas(topic, partition)
+		if err != nil {
+			continue
+		}
+		isr, err := c.InSyncReplicas(topic, partition)
+		if err != nil {
+			continue
+		}
+		if len(isr) < len(replicas) {
+			underReplicated++
+		}
+		if minInSync < 0 || len(isr) < minInSync {
+			minInSync = len(isr)
+		}
+	}
+	return underReplicated, minInSync
+}
+
 func (c *Client) partitionsForTopic(topic string) []int32 {
 	partitions, err := c.Partitions(topic)
 	if err != nil {
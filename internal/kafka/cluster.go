@@ -0,0 +1,272 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	// ConfigMinInSyncReplicas is the topic configuration of the fewest
+	// in-sync replicas an acks=all produce needs
+	ConfigMinInSyncReplicas = "min.insync.replicas"
+	// ConfigRetentionMs is the topic configuration of how long records are
+	// kept, -1 for ever
+	ConfigRetentionMs = "retention.ms"
+
+	// timeLagConcurrency bounds the time lags read at once
+	timeLagConcurrency = 8
+)
+
+// ClusterState is a snapshot of the metadata, configuration and consumer
+// group offsets of a cluster
+type ClusterState struct {
+	Brokers []int32
+	Topics  map[string]*TopicState
+	Groups  []GroupState
+	At      time.Time
+}
+
+// TopicState is the metadata, offsets and configuration of a topic
+type TopicState struct {
+	Name       string
+	Partitions []PartitionState
+	Config     map[string]string
+}
+
+// PartitionState is the metadata and offsets of a partition. Leader is -1
+// when the partition has no leader.
+type PartitionState struct {
+	Partition int32
+	Leader    int32
+	Replicas  []int32
+	InSync    []int32
+	PartitionOffsets
+}
+
+// ConfigInt returns an integer configuration of the topic, false when it is
+// unknown
+func (t *TopicState) ConfigInt(name string) (int64, bool) {
+	value, err := strconv.ParseInt(t.Config[name], 10, 64)
+	return value, err == nil
+}
+
+// ReplicationFactor returns the replicas of the first partition
+func (t *TopicState) ReplicationFactor() int {
+	if len(t.Partitions) == 0 {
+		return 0
+	}
+	return len(t.Partitions[0].Replicas)
+}
+
+// GroupState is the state of a consumer group and the offsets it committed
+type GroupState struct {
+	ID      string
+	State   string
+	Members int
+	// Subscribed are the topics the members subscribe to
+	Subscribed map[string]bool
+	// Offsets maps the topics and partitions the group committed offsets on
+	// to the committed offsets
+	Offsets map[string]map[int32]GroupPartition
+}
+
+// GroupPartition is the committed offset of a group on a partition, and how
+// far behind in time it is when it lags
+type GroupPartition struct {
+	Committed    int64
+	TimeLag      time.Duration
+	TimeLagKnown bool
+}
+
+// ClusterState collects the state of every topic and consumer group of the
+// cluster. The time lags are only read for the topics with a time based
+// retention. What cannot be collected is left out and reported in the
+// returned error.
+func (c *Client) ClusterState() (*ClusterState, error) {
+	topics, err := c.ListTopics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+
+	state := &ClusterState{
+		Topics: make(map[string]*TopicState, len(topics)),
+		At:     time.Now(),
+	}
+	for _, broker := range c.Brokers() {
+		state.Brokers = append(state.Brokers, broker.ID())
+	}
+	sort.Slice(state.Brokers, func(i, j int) bool { return state.Brokers[i] < state.Brokers[j] })
+
+	offsets, offsetsErr := c.ListOffsets(topics)
+	configs, configsErr := c.TopicConfigs(topics, ConfigMinInSyncReplicas, ConfigRetentionMs)
+	errs := []error{offsetsErr, configsErr}
+
+	for topic, partitionOffsets := range offsets {
+		topicState := &TopicState{Name: topic, Config: configs[topic]}
+		for partition, offsets := range partitionOffsets {
+			partitionState := PartitionState{Partition: partition, Leader: -1, PartitionOffsets: offsets}
+			if leader, err := c.Leader(topic, partition); err == nil {
+				partitionState.Leader = leader.ID()
+			}
+			partitionState.Replicas, _ = c.Replicas(topic, partition)
+			partitionState.InSync, _ = c.InSyncReplicas(topic, partition)
+			topicState.Partitions = append(topicState.Partitions, partitionState)
+		}
+		sort.Slice(topicState.Partitions, func(i, j int) bool {
+			return topicState.Partitions[i].Partition < topicState.Partitions[j].Partition
+		})
+		state.Topics[topic] = topicState
+	}
+
+	groups, err := c.listConsumerGroups()
+	errs = append(errs, err)
+	for _, group := range groups {
+		groupState, err := c.groupState(group)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", group, err))
+			continue
+		}
+		state.Groups = append(state.Groups, groupState)
+	}
+	c.readTimeLags(state)
+
+	return state, errors.Join(errs...)
+}
+
+// listConsumerGroups lists the consumer groups of every broker
+func (c *Client) listConsumerGroups() ([]string, error) {
+	var groups []string
+	var errs []error
+	for _, broker := range c.Brokers() {
+		if err := broker.Open(c.Config()); err != nil && !errors.Is(err, sarama.ErrAlreadyConnected) {
+			errs = append(errs, fmt.Errorf("broker %d: %w", broker.ID(), err))
+			continue
+		}
+		response, err := broker.ListGroups(&sarama.ListGroupsRequest{})
+		if err == nil && response.Err != sarama.ErrNoError {
+			err = response.Err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list groups of broker %d: %w", broker.ID(), err))
+			continue
+		}
+		for group, protocolType := range response.Groups {
+			if protocolType == "consumer" || protocolType == "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups, errors.Join(errs...)
+}
+
+// groupState describes a consumer group and fetches the offsets it
+// committed on every partition
+func (c *Client) groupState(group string) (GroupState, error) {
+	coordinator, err := c.Coordinator(group)
+	if err != nil {
+		return GroupState{}, fmt.Errorf("failed to get coordinator: %w", err)
+	}
+
+	describe, err := coordinator.DescribeGroups(&sarama.DescribeGroupsRequest{Groups: []string{group}})
+	if err != nil {
+		return GroupState{}, fmt.Errorf("failed to describe group: %w", err)
+	}
+	if len(describe.Groups) == 0 {
+		return GroupState{}, fmt.Errorf("group not described")
+	}
+
+	description := describe.Groups[0]
+	state := GroupState{
+		ID:         group,
+		State:      description.State,
+		Members:    len(description.Members),
+		Subscribed: make(map[string]bool),
+		Offsets:    make(map[string]map[int32]GroupPartition),
+	}
+	for _, member := range description.Members {
+		metadata, err := member.GetMemberMetadata()
+		if err != nil || metadata == nil {
+			continue
+		}
+		for _, topic := range metadata.Topics {
+			state.Subscribed[topic] = true
+		}
+	}
+
+	// version 2 without partitions fetches the offsets of every partition
+	fetch, err := coordinator.FetchOffset(&sarama.OffsetFetchRequest{Version: 2, ConsumerGroup: group})
+	if err != nil {
+		return GroupState{}, fmt.Errorf("failed to fetch offsets: %w", err)
+	}
+	for topic, partitions := range fetch.Blocks {
+		for partition, block := range partitions {
+			if block.Err != sarama.ErrNoError || block.Offset < 0 {
+				continue
+			}
+			if state.Offsets[topic] == nil {
+				state.Offsets[topic] = make(map[int32]GroupPartition)
+			}
+			state.Offsets[topic][partition] = GroupPartition{Committed: block.Offset}
+		}
+	}
+	return state, nil
+}
+
+// readTimeLags reads the time lags of the lagging partitions of the topics
+// with a time based retention, a few partitions at once
+func (c *Client) readTimeLags(state *ClusterState) {
+	type lagging struct {
+		group     GroupState
+		topic     string
+		partition int32
+	}
+
+	var partitions []lagging
+	for _, group := range state.Groups {
+		for topic, offsets := range group.Offsets {
+			topicState, ok := state.Topics[topic]
+			if !ok {
+				continue
+			}
+			if retention, ok := topicState.ConfigInt(ConfigRetentionMs); !ok || retention <= 0 {
+				continue
+			}
+			for _, partitionState := range topicState.Partitions {
+				offset, ok := offsets[partitionState.Partition]
+				if ok && partitionState.Err == nil && offset.Committed < partitionState.Newest {
+					partitions = append(partitions, lagging{group, topic, partitionState.Partition})
+				}
+			}
+		}
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, timeLagConcurrency)
+	for _, p := range partitions {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(p lagging) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			mutex.Lock()
+			offset := p.group.Offsets[p.topic][p.partition]
+			mutex.Unlock()
+
+			offset.TimeLag, offset.TimeLagKnown = c.timeLag(p.topic, p.partition, offset.Committed, state.At)
+
+			mutex.Lock()
+			p.group.Offsets[p.topic][p.partition] = offset
+			mutex.Unlock()
+		}(p)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/clemsau/kafe/internal/models"
+)
+
+// KeyTable materializes a compacted topic: the latest record of every key,
+// keys whose latest record is a tombstone being deleted
+type KeyTable struct {
+	search *Search
+	mutex  sync.Mutex
+	// latest holds the latest record of each key by raw key, tombstones
+	// included so that older records from other partitions are ignored
+	latest  map[string]models.Message
+	deleted int
+}
+
+// NewKeyTable prepares a scan of the topic from the earliest records to the
+// current end offsets
+func (c *Client) NewKeyTable(topic string) (*KeyTable, error) {
+	search, err := c.NewSearch(topic, Position{Offset: sarama.OffsetOldest}, Position{Offset: sarama.OffsetNewest}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyTable{
+		search: search,
+		latest: make(map[string]models.Message),
+	}, nil
+}
+
+// Run scans the topic and builds the table. It blocks until the scan is
+// done or ctx is cancelled.
+func (t *KeyTable) Run(ctx context.Context) error {
+	return t.search.Run(ctx, t.apply)
+}
+
+// apply records a message as the latest one of its key. Records of a key
+// arrive in offset order within a partition, so only records from another
+// partition are compared by timestamp.
+func (t *KeyTable) apply(msg models.Message) {
+	if msg.Key == nil {
+		return
+	}
+	key := string(msg.Key)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	prev, ok := t.latest[key]
+	if ok && prev.Partition != msg.Partition && prev.Timestamp.After(msg.Timestamp) {
+		return
+	}
+
+	switch {
+	case ok && prev.Value == nil && msg.Value != nil:
+		t.deleted--
+	case (!ok || prev.Value != nil) && msg.Value == nil:
+		t.deleted++
+	}
+	t.latest[key] = msg
+}
+
+// Progress returns the progress of the scan
+func (t *KeyTable) Progress() SearchProgress {
+	return t.search.Progress()
+}
+
+// Keys returns the latest record of every live key, in key order
+func (t *KeyTable) Keys() []models.Message {
+	t.mutex.Lock()
+	keys := make([]models.Message, 0, len(t.latest)-t.deleted)
+	for _, msg := range t.latest {
+		if msg.Value != nil {
+			keys = append(keys, msg)
+		}
+	}
+	t.mutex.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].KeyText() < keys[j].KeyText()
+	})
+	return keys
+}
+
+// Counts returns the number of live keys and of keys deleted by tombstones
+func (t *KeyTable) Counts() (live int, deleted int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.latest) - t.deleted, t.deleted
+}
+
+// TopicConfig returns the values of the named configuration entries of a topic
+func (c *Client) TopicConfig(topic string, names ...string) (map[string]string, error) {
+	configs, err := c.TopicConfigs([]string{topic}, names...)
+	if config, ok := configs[topic]; ok {
+		return config, nil
+	}
+	return nil, err
+}
+
+// TopicConfigs returns the values of the named configuration entries of the
+// topics, in a single request. Topics whose configuration cannot be
+// described are left out and reported in the returned error.
+func (c *Client) TopicConfigs(topics []string, names ...string) (map[string]map[string]string, error) {
+	broker, err := c.Controller()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get controller: %w", err)
+	}
+
+	resources := make([]*sarama.ConfigResource, 0, len(topics))
+	for _, topic := range topics {
+		resources = append(resources, &sarama.ConfigResource{
+			Type:        sarama.TopicResource,
+			Name:        topic,
+			ConfigNames: names,
+		})
+	}
+	response, err := broker.DescribeConfigs(&sarama.DescribeConfigsRequest{Resources: resources})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topic config: %w", err)
+	}
+
+	configs := make(map[string]map[string]string, len(response.Resources))
+	var errs []error
+	for _, resource := range response.Resources {
+		if resource.ErrorCode != 0 {
+			errs = append(errs, fmt.Errorf("failed to describe topic config of %s: %w", resource.Name, sarama.KError(resource.ErrorCode)))
+			continue
+		}
+		config := make(map[string]string, len(resource.Configs))
+		for _, entry := range resource.Configs {
+			config[entry.Name] = entry.Value
+		}
+		configs[resource.Name] = config
+	}
+	return configs, errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"context"
+	"io"
+
+	"github.com/clemsau/kafe/internal/filter"
+	"github.com/clemsau/kafe/internal/models"
+)
+
+// CopyOptions configures a copy job
+type CopyOptions struct {
+	BulkOptions
+	From   Position
+	To     Position
+	Filter *filter.Filter
+	// KeepPartitions produces each message to its source partition,
+	// instead of letting the partitioner choose
+	KeepPartitions bool
+	// KeepTimestamps preserves the source timestamps, instead of stamping
+	// the copies with the time they are produced
+	KeepTimestamps bool
+}
+
+// CopyJob copies the messages of a source topic range matching a filter to
+// a destination topic, possibly on another cluster. Keys, values and
+// headers are copied as is.
+type CopyJob struct {
+	search    *Search
+	dest      *Client
+	destTopic string
+	opts      CopyOptions
+}
+
+// NewCopyJob prepares a copy of topic to destTopic through dest, resolving
+// the source range at once like a search
+func (c *Client) NewCopyJob(topic string, dest *Client, destTopic string, opts CopyOptions) (*CopyJob, error) {
+	search, err := c.NewSearch(topic, opts.From, opts.To, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CopyJob{
+		search:    search,
+		dest:      dest,
+		destTopic: destTopic,
+		opts:      opts,
+	}, nil
+}
+
+// Progress returns the progress of the scan of the source topic
+func (j *CopyJob) Progress() SearchProgress {
+	return j.search.Progress()
+}
+
+// Run copies the messages until the end of the source range or until ctx
+// is cancelled. progress, when set, is called after each produced batch.
+func (j *CopyJob) Run(ctx context.Context, progress func(BulkResult)) (BulkResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	messages := make(chan models.Message, bulkBatchSize)
+	scanErr := make(chan error, 1)
+	go func() {
+		scanErr <- j.search.Run(ctx, func(msg models.Message) {
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+			}
+		})
+		close(messages)
+	}()
+
+	line := 0
+	next := func() (BulkRecord, error) {
+		msg, ok := <-messages
+		if !ok {
+			return BulkRecord{}, io.EOF
+		}
+		line++
+
+		record := BulkRecord{
+			Line: line,
+			Message: models.Message{
+				Key:     msg.Key,
+				Value:   msg.Value,
+				Headers: msg.Headers,
+			},
+			Partition: AnyPartition,
+		}
+		if j.opts.KeepTimestamps {
+			record.Message.Timestamp = msg.Timestamp
+		}
+		if j.opts.KeepPartitions {
+			record.Partition = msg.Partition
+		}
+		return record, nil
+	}
+
+	result, err := j.dest.ProduceBulk(ctx, j.destTopic, next, j.opts.BulkOptions, progress)
+	cancel()
+	for range messages {
+		// drain so the scan stops
+	}
+	if err != nil {
+		return result, err
+	}
+	if err := <-scanErr; err != nil && err != context.Canceled {
+		return result, err
+	}
+	return result, nil
+}
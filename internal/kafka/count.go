@@ -0,0 +1,208 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// countFetchBytes is how many bytes of batches a count fetches at once
+	countFetchBytes = 4 * 1024 * 1024
+	// countConcurrency bounds the partitions counted at once
+	countConcurrency = 8
+)
+
+// PartitionCount tracks the exact count of the records of a partition
+type PartitionCount struct {
+	Partition int32
+	// Start and End are the log start offset and high watermark captured
+	// when the count was created
+	Start   int64
+	End     int64
+	Scanned int64
+	// Records counts the data records, the aborted ones included
+	Records int64
+	Aborted int64
+	// Markers counts the transaction markers
+	Markers int64
+	Done    bool
+	Err     error
+}
+
+// Span returns the offset span of the partition
+func (p PartitionCount) Span() int64 {
+	return p.End - p.Start
+}
+
+// RecordCount is a snapshot of a running count
+type RecordCount struct {
+	Partitions []PartitionCount
+	Span       int64
+	Scanned    int64
+	Records    int64
+	Aborted    int64
+	Markers    int64
+	Elapsed    time.Duration
+	Done       bool
+}
+
+// RecordCounter counts the records of a topic by reading its record batches,
+// so the offsets left by compaction, transaction markers and aborted
+// transactions are told apart from the records. The high watermarks are
+// captured when the counter is created.
+type RecordCounter struct {
+	client     *Client
+	topic      string
+	partitions []*PartitionCount
+	started    time.Time
+	finished   time.Time
+	mutex      sync.Mutex
+}
+
+// NewRecordCounter resolves the offsets of every partition of the topic
+func (c *Client) NewRecordCounter(topic string) (*RecordCounter, error) {
+	offsets, err := c.ListOffsets([]string{topic})
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &RecordCounter{client: c, topic: topic}
+	for partition, p := range offsets[topic] {
+		if p.Err != nil {
+			return nil, fmt.Errorf("failed to get the offsets of partition %d: %w", partition, p.Err)
+		}
+		counter.partitions = append(counter.partitions, &PartitionCount{
+			Partition: partition,
+			Start:     p.Oldest,
+			End:       p.Newest,
+		})
+	}
+	sort.Slice(counter.partitions, func(i, j int) bool {
+		return counter.partitions[i].Partition < counter.partitions[j].Partition
+	})
+	return counter, nil
+}
+
+// Run counts the records of every partition, a few partitions at a time. It
+// blocks until all partitions are counted or ctx is cancelled.
+func (rc *RecordCounter) Run(ctx context.Context) error {
+	rc.mutex.Lock()
+	rc.started = time.Now()
+	rc.mutex.Unlock()
+
+	slots := make(chan struct{}, countConcurrency)
+	var wg sync.WaitGroup
+	for _, progress := range rc.partitions {
+		wg.Add(1)
+		go func(progress *PartitionCount) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				rc.finish(progress, ctx.Err())
+				return
+			}
+			rc.finish(progress, rc.countPartition(ctx, progress))
+		}(progress)
+	}
+	wg.Wait()
+
+	rc.mutex.Lock()
+	rc.finished = time.Now()
+	rc.mutex.Unlock()
+	return ctx.Err()
+}
+
+func (rc *RecordCounter) countPartition(ctx context.Context, progress *PartitionCount) error {
+	offset := progress.Start
+	for offset < progress.End {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page, err := rc.client.FetchBatches(rc.topic, progress.Partition, offset, countFetchBytes)
+		if err != nil {
+			return err
+		}
+		if len(page.Batches) == 0 {
+			if offset < page.LogStartOffset {
+				// the records were deleted since the count started
+				offset = page.LogStartOffset
+				continue
+			}
+			// the broker returned nothing below the end offset, the count
+			// would silently miss the remaining records
+			return fmt.Errorf("stopped at offset %d of %d", offset, progress.End)
+		}
+
+		var records, aborted, markers int64
+		for _, batch := range page.Batches {
+			if batch.FirstOffset >= progress.End {
+				break
+			}
+			for _, record := range batch.Records {
+				if record.Offset < offset || record.Offset >= progress.End {
+					continue
+				}
+				switch {
+				case batch.Control:
+					markers++
+				case batch.Aborted:
+					records++
+					aborted++
+				default:
+					records++
+				}
+			}
+		}
+		offset = page.Next
+
+		rc.mutex.Lock()
+		progress.Records += records
+		progress.Aborted += aborted
+		progress.Markers += markers
+		progress.Scanned = min(offset, progress.End) - progress.Start
+		rc.mutex.Unlock()
+	}
+	return nil
+}
+
+func (rc *RecordCounter) finish(progress *PartitionCount, err error) {
+	if err == context.Canceled {
+		return
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	progress.Done = true
+	progress.Err = err
+}
+
+// Progress returns a snapshot of the count
+func (rc *RecordCounter) Progress() RecordCount {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	count := RecordCount{Done: !rc.finished.IsZero()}
+	for _, p := range rc.partitions {
+		count.Partitions = append(count.Partitions, *p)
+		count.Span += p.Span()
+		count.Scanned += p.Scanned
+		count.Records += p.Records
+		count.Aborted += p.Aborted
+		count.Markers += p.Markers
+	}
+
+	switch {
+	case rc.started.IsZero():
+	case count.Done:
+		count.Elapsed = rc.finished.Sub(rc.started)
+	default:
+		count.Elapsed = time.Since(rc.started)
+	}
+	return count
+}
@@ -0,0 +1,85 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	// listOffsetsIsolationVersion is the first list offsets version taking
+	// an isolation level
+	listOffsetsIsolationVersion = 2
+	// abortScanBytes is how many bytes of batches a scan for aborted
+	// transactions fetches at once
+	abortScanBytes = 1024 * 1024
+)
+
+// OffsetRange is an inclusive range of offsets
+type OffsetRange struct {
+	First int64
+	Last  int64
+}
+
+// Contains tells whether offset is in the range
+func (r OffsetRange) Contains(offset int64) bool {
+	return offset >= r.First && offset <= r.Last
+}
+
+// LastStableOffset returns the offset of a partition below which all
+// transactions are committed or aborted
+func (c *Client) LastStableOffset(topic string, partition int32) (int64, error) {
+	broker, err := c.Leader(topic, partition)
+	if err != nil {
+		return 0, err
+	}
+
+	request := &sarama.OffsetRequest{
+		Version:        listOffsetsIsolationVersion,
+		IsolationLevel: sarama.ReadCommitted,
+	}
+	request.AddBlock(topic, partition, sarama.OffsetNewest, 1)
+
+	response, err := broker.GetAvailableOffsets(request)
+	if err != nil {
+		return 0, err
+	}
+	block := response.GetBlock(topic, partition)
+	if block == nil {
+		return 0, fmt.Errorf("no offset returned for partition %d", partition)
+	}
+	if block.Err != sarama.ErrNoError {
+		return 0, block.Err
+	}
+	return block.Offset, nil
+}
+
+// AbortedRanges scans the batches of a partition from offset from up to
+// offset to, excluded, and returns the offsets of the aborted transactional
+// batches. The scan stops at the last stable offset, as later transactions
+// are not decided yet, and returns the offset the next scan starts from.
+func (c *Client) AbortedRanges(topic string, partition int32, from, to int64) ([]OffsetRange, int64, error) {
+	var aborted []OffsetRange
+	for from < to {
+		page, err := c.FetchBatches(topic, partition, from, abortScanBytes)
+		if err != nil {
+			return aborted, from, err
+		}
+		if len(page.Batches) == 0 {
+			break
+		}
+
+		for _, batch := range page.Batches {
+			if batch.LastOffset >= to || batch.LastOffset >= page.LastStableOffset {
+				// the batch is not fully consumed or its transaction is
+				// not decided yet, scan it again later
+				return aborted, from, nil
+			}
+			if batch.Aborted {
+				aborted = append(aborted, OffsetRange{First: batch.FirstOffset, Last: batch.LastOffset})
+			}
+			from = batch.LastOffset + 1
+		}
+	}
+	return aborted, from, nil
+}
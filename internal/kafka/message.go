@@ -0,0 +1,31 @@
+package kafka
+
+import (
+	"github.com/clemsau/kafe/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+// NewMessage converts a consumed sarama message into a models.Message
+func NewMessage(msg *sarama.ConsumerMessage) models.Message {
+	message := models.Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+		Key:       msg.Key,
+		Value:     msg.Value,
+	}
+
+	for _, header := range msg.Headers {
+		if header == nil {
+			continue
+		}
+		message.Headers = append(message.Headers, models.MessageHeader{
+			Key:   string(header.Key),
+			Value: header.Value,
+		})
+	}
+
+	return message
+}
@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// PartitionOffsets holds the log start offset, high watermark and last
+// stable offset of a partition
+type PartitionOffsets struct {
+	Oldest     int64
+	Newest     int64
+	LastStable int64
+	Err        error
+}
+
+// TopicOffsets maps the partitions of a topic to their offsets
+type TopicOffsets map[int32]PartitionOffsets
+
+// leaderPartitions are the partitions led by a broker
+type leaderPartitions struct {
+	broker     *sarama.Broker
+	partitions map[string][]int32
+	offsets    map[string]TopicOffsets
+}
+
+// ListOffsets fetches the offsets of every partition of the topics. The
+// partitions are grouped by leader and the brokers are queried
+// concurrently, each one receiving a single request per kind of offset
+// for all the partitions it leads. Topics whose partitions cannot be
+// listed are left out and reported in the returned error.
+func (c *Client) ListOffsets(topics []string) (map[string]TopicOffsets, error) {
+	result := make(map[string]TopicOffsets, len(topics))
+	leaders := make(map[int32]*leaderPartitions)
+	var errs []error
+
+	for _, topic := range topics {
+		partitions, err := c.Partitions(topic)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("topic %s: %w", topic, err))
+			continue
+		}
+
+		result[topic] = make(TopicOffsets, len(partitions))
+		for _, partition := range partitions {
+			broker, err := c.Leader(topic, partition)
+			if err != nil {
+				result[topic][partition] = PartitionOffsets{Err: err}
+				continue
+			}
+
+			leader, ok := leaders[broker.ID()]
+			if !ok {
+				leader = &leaderPartitions{broker: broker, partitions: make(map[string][]int32)}
+				leaders[broker.ID()] = leader
+			}
+			leader.partitions[topic] = append(leader.partitions[topic], partition)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, leader := range leaders {
+		wg.Add(1)
+		go func(leader *leaderPartitions) {
+			defer wg.Done()
+			leader.offsets = leader.list()
+		}(leader)
+	}
+	wg.Wait()
+
+	for _, leader := range leaders {
+		for topic, offsets := range leader.offsets {
+			for partition, partitionOffsets := range offsets {
+				result[topic][partition] = partitionOffsets
+			}
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// list sends the oldest, newest and last stable offset requests to the
+// leader
+func (l *leaderPartitions) list() map[string]TopicOffsets {
+	offsets := make(map[string]TopicOffsets, len(l.partitions))
+	for topic, partitions := range l.partitions {
+		offsets[topic] = make(TopicOffsets, len(partitions))
+	}
+
+	oldest, oldestErr := l.request(sarama.OffsetOldest, sarama.ReadUncommitted)
+	newest, newestErr := l.request(sarama.OffsetNewest, sarama.ReadUncommitted)
+	lastStable, lastStableErr := l.request(sarama.OffsetNewest, sarama.ReadCommitted)
+
+	for topic, partitions := range l.partitions {
+		for _, partition := range partitions {
+			var p PartitionOffsets
+			p.Oldest, p.Err = offsetOf(oldest, oldestErr, topic, partition)
+			if p.Err == nil {
+				p.Newest, p.Err = offsetOf(newest, newestErr, topic, partition)
+			}
+			if p.Err == nil {
+				var err error
+				p.LastStable, err = offsetOf(lastStable, lastStableErr, topic, partition)
+				if err != nil {
+					p.LastStable = p.Newest
+				}
+			}
+			offsets[topic][partition] = p
+		}
+	}
+	return offsets
+}
+
+func (l *leaderPartitions) request(time int64, isolation sarama.IsolationLevel) (*sarama.OffsetResponse, error) {
+	request := &sarama.OffsetRequest{
+		Version:        listOffsetsIsolationVersion,
+		IsolationLevel: isolation,
+	}
+	for topic, partitions := range l.partitions {
+		for _, partition := range partitions {
+			request.AddBlock(topic, partition, time, 1)
+		}
+	}
+	return l.broker.GetAvailableOffsets(request)
+}
+
+func offsetOf(response *sarama.OffsetResponse, err error, topic string, partition int32) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	block := response.GetBlock(topic, partition)
+	if block == nil {
+		return 0, fmt.Errorf("no offset returned for partition %d", partition)
+	}
+	if block.Err != sarama.ErrNoError {
+		return 0, block.Err
+	}
+	return block.Offset, nil
+}
@@ -0,0 +1,158 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clemsau/kafe/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+// AnyPartition lets the producer's partitioner choose the partition
+const AnyPartition int32 = -1
+
+// Partitioners lists the partitioner names accepted by ProducerOptions
+var Partitioners = []string{"hash", "random", "round-robin"}
+
+// Compressions lists the compression codecs accepted by ProducerOptions
+var Compressions = []string{"none", "gzip", "snappy", "lz4", "zstd"}
+
+// ProducerOptions configures a producer built from the client's config
+type ProducerOptions struct {
+	Partitioner string
+	Compression string
+	Idempotent  bool
+	// TransactionalID makes the producer transactional, which implies Idempotent
+	TransactionalID string
+}
+
+// Producer sends messages synchronously and reports where they were written
+type Producer struct {
+	sarama.SyncProducer
+}
+
+// NewProducer creates a producer sharing the client's connection settings
+func (c *Client) NewProducer(opts ProducerOptions) (*Producer, error) {
+	config := *c.Config()
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+
+	partitioner, err := partitionerFor(opts.Partitioner)
+	if err != nil {
+		return nil, err
+	}
+	config.Producer.Partitioner = partitioner
+
+	codec, err := compressionFor(opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	config.Producer.Compression = codec
+
+	if opts.Idempotent || opts.TransactionalID != "" {
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+		if config.Producer.Retry.Max == 0 {
+			config.Producer.Retry.Max = 3
+		}
+	}
+	if opts.TransactionalID != "" {
+		config.Producer.Transaction.ID = opts.TransactionalID
+	}
+
+	producer, err := sarama.NewSyncProducer(c.addresses, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create producer: %w", err)
+	}
+
+	return &Producer{SyncProducer: producer}, nil
+}
+
+// Send produces the message to msg.Topic. A partition other than
+// AnyPartition bypasses the partitioner.
+func (p *Producer) Send(msg models.Message, partition int32) (int32, int64, error) {
+	return p.SendMessage(newProducerMessage(msg, partition))
+}
+
+func newProducerMessage(msg models.Message, partition int32) *sarama.ProducerMessage {
+	pm := &sarama.ProducerMessage{
+		Topic:     msg.Topic,
+		Value:     sarama.ByteEncoder(msg.Value),
+		Timestamp: msg.Timestamp,
+		Metadata:  recordMetadata{partition: partition},
+	}
+	if msg.Key != nil {
+		pm.Key = sarama.ByteEncoder(msg.Key)
+	}
+	for _, header := range msg.Headers {
+		pm.Headers = append(pm.Headers, sarama.RecordHeader{
+			Key:   []byte(header.Key),
+			Value: header.Value,
+		})
+	}
+	return pm
+}
+
+// recordMetadata travels with a produced message through the partitioner and
+// back in producer errors
+type recordMetadata struct {
+	partition int32
+	line      int
+}
+
+// explicitPartitioner honours the partition requested in a message's
+// metadata and defers to the fallback partitioner otherwise
+type explicitPartitioner struct {
+	fallback sarama.Partitioner
+}
+
+func (p *explicitPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if metadata, ok := msg.Metadata.(recordMetadata); ok && metadata.partition != AnyPartition {
+		partition := metadata.partition
+		if partition >= numPartitions {
+			return 0, fmt.Errorf("partition %d does not exist, topic has %d partitions", partition, numPartitions)
+		}
+		return partition, nil
+	}
+	return p.fallback.Partition(msg, numPartitions)
+}
+
+func (p *explicitPartitioner) RequiresConsistency() bool {
+	return p.fallback.RequiresConsistency()
+}
+
+func partitionerFor(name string) (sarama.PartitionerConstructor, error) {
+	var fallback sarama.PartitionerConstructor
+	switch strings.ToLower(name) {
+	case "", "hash":
+		fallback = sarama.NewHashPartitioner
+	case "random":
+		fallback = sarama.NewRandomPartitioner
+	case "round-robin", "roundrobin":
+		fallback = sarama.NewRoundRobinPartitioner
+	default:
+		return nil, fmt.Errorf("unknown partitioner %q", name)
+	}
+
+	return func(topic string) sarama.Partitioner {
+		return &explicitPartitioner{fallback: fallback(topic)}
+	}, nil
+}
+
+func compressionFor(name string) (sarama.CompressionCodec, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionZSTD, nil
+	}
+	return sarama.CompressionNone, fmt.Errorf("unknown compression %q", name)
+}
@@ -0,0 +1,345 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/filter"
+	"github.com/clemsau/kafe/internal/models"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	// searchIdleTimeout is how long a partition may stay silent before its
+	// remaining offsets are checked for records: offsets compacted away and
+	// transaction markers are never delivered to consumers
+	searchIdleTimeout = 3 * time.Second
+	// searchCheckMaxBytes bounds each fetch of the remaining offsets check
+	searchCheckMaxBytes = 1024 * 1024
+)
+
+// Position is a point in a partition log, either an offset or a timestamp
+type Position struct {
+	Offset int64
+	Time   time.Time
+}
+
+// ParsePosition parses `earliest`, `latest`, `@<offset>` or any time
+// accepted by filter.ParseTime
+func ParsePosition(text string, now time.Time) (Position, error) {
+	text = strings.TrimSpace(text)
+	switch strings.ToLower(text) {
+	case "earliest", "oldest", "beginning":
+		return Position{Offset: sarama.OffsetOldest}, nil
+	case "latest", "newest", "end", "":
+		return Position{Offset: sarama.OffsetNewest}, nil
+	}
+
+	if strings.HasPrefix(text, "@") {
+		offset, err := strconv.ParseInt(text[1:], 10, 64)
+		if err != nil || offset < 0 {
+			return Position{}, fmt.Errorf("invalid offset %q", text)
+		}
+		return Position{Offset: offset}, nil
+	}
+
+	ts, err := filter.ParseTime(text, now)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Time: ts}, nil
+}
+
+// ResolveOffset returns the offset of the position in a partition, clamped
+// to the partition's current oldest and newest offsets
+func (c *Client) ResolveOffset(topic string, partition int32, pos Position) (int64, error) {
+	oldest, err := c.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, err
+	}
+	newest, err := c.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, err
+	}
+	return c.resolveBetween(topic, partition, pos, oldest, newest)
+}
+
+// resolveBetween resolves a position of a partition whose log start offset
+// and high watermark are known
+func (c *Client) resolveBetween(topic string, partition int32, pos Position, oldest, newest int64) (int64, error) {
+	offset := pos.Offset
+	if !pos.Time.IsZero() {
+		var err error
+		offset, err = c.GetOffset(topic, partition, pos.Time.UnixMilli())
+		if err != nil {
+			return 0, err
+		}
+		if offset < 0 { // no message at or after the timestamp
+			offset = newest
+		}
+	}
+
+	switch {
+	case offset == sarama.OffsetOldest || offset < oldest:
+		return oldest, nil
+	case offset == sarama.OffsetNewest || offset > newest:
+		return newest, nil
+	}
+	return offset, nil
+}
+
+// PartitionProgress tracks a search over a single partition
+type PartitionProgress struct {
+	Partition int32
+	Start     int64
+	End       int64
+	Scanned   int64
+	Matched   int64
+	Done      bool
+	Err       error
+}
+
+// Remaining returns the number of offsets left to scan
+func (p PartitionProgress) Remaining() int64 {
+	if p.Done {
+		return 0
+	}
+	return max(0, p.End-p.Start-p.Scanned)
+}
+
+// SearchProgress is a snapshot of a running search
+type SearchProgress struct {
+	Partitions []PartitionProgress
+	Scanned    int64
+	Matched    int64
+	Total      int64
+	Elapsed    time.Duration
+	Done       bool
+}
+
+// ETA estimates the remaining duration from the current scan rate
+func (p SearchProgress) ETA() time.Duration {
+	if p.Done || p.Scanned == 0 {
+		return 0
+	}
+	var remaining int64
+	for _, partition := range p.Partitions {
+		remaining += partition.Remaining()
+	}
+	rate := float64(p.Scanned) / p.Elapsed.Seconds()
+	return time.Duration(float64(remaining) / rate * float64(time.Second))
+}
+
+// Search scans a bounded range of a topic across all partitions in parallel.
+// End offsets are captured when the search is created, so messages produced
+// while it runs are not scanned.
+type Search struct {
+	client     *Client
+	topic      string
+	filter     *filter.Filter
+	partitions []*PartitionProgress
+	started    time.Time
+	finished   time.Time
+	mutex      sync.Mutex
+}
+
+// NewSearch resolves the start and end offsets of every partition of the topic
+func (c *Client) NewSearch(topic string, from, to Position, f *filter.Filter) (*Search, error) {
+	partitions, err := c.Partitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+
+	search := &Search{
+		client: c,
+		topic:  topic,
+		filter: f,
+	}
+
+	for _, partition := range partitions {
+		start, err := c.ResolveOffset(topic, partition, from)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve start offset of partition %d: %w", partition, err)
+		}
+		end, err := c.ResolveOffset(topic, partition, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve end offset of partition %d: %w", partition, err)
+		}
+
+		search.partitions = append(search.partitions, &PartitionProgress{
+			Partition: partition,
+			Start:     start,
+			End:       max(start, end),
+		})
+	}
+
+	return search, nil
+}
+
+// Topic returns the searched topic
+func (s *Search) Topic() string {
+	return s.topic
+}
+
+// Run scans every partition and calls onMatch for each matching message. It
+// blocks until all partitions reached their end offset or ctx is cancelled.
+// onMatch may be called concurrently.
+func (s *Search) Run(ctx context.Context, onMatch func(models.Message)) error {
+	consumer, err := sarama.NewConsumerFromClient(s.client.Client)
+	if err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	s.mutex.Lock()
+	s.started = time.Now()
+	s.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, progress := range s.partitions {
+		if progress.Start >= progress.End {
+			s.finish(progress, nil)
+			continue
+		}
+
+		wg.Add(1)
+		go func(progress *PartitionProgress) {
+			defer wg.Done()
+			s.finish(progress, s.scanPartition(ctx, consumer, progress, onMatch))
+		}(progress)
+	}
+	wg.Wait()
+
+	s.mutex.Lock()
+	s.finished = time.Now()
+	s.mutex.Unlock()
+
+	return ctx.Err()
+}
+
+func (s *Search) scanPartition(ctx context.Context, consumer sarama.Consumer, progress *PartitionProgress, onMatch func(models.Message)) error {
+	pc, err := consumer.ConsumePartition(s.topic, progress.Partition, progress.Start)
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+
+	idle := time.NewTicker(searchIdleTimeout)
+	defer idle.Stop()
+	received := false
+	next := progress.Start
+
+	for {
+		select {
+		case msg := <-pc.Messages():
+			received = true
+			next = msg.Offset + 1
+			if msg.Offset >= progress.End {
+				return nil
+			}
+
+			message := NewMessage(msg)
+			s.client.DecodeMessage(&message)
+			matched := s.filter.Match(&message)
+
+			s.mutex.Lock()
+			progress.Scanned = msg.Offset - progress.Start + 1
+			if matched {
+				progress.Matched++
+			}
+			s.mutex.Unlock()
+
+			if matched {
+				onMatch(message)
+			}
+			if msg.Offset >= progress.End-1 {
+				return nil
+			}
+		case err := <-pc.Errors():
+			return err
+		case <-idle.C:
+			if received || pc.HighWaterMarkOffset() < progress.End {
+				received = false
+				continue
+			}
+			pending, err := s.client.pendingRecords(s.topic, progress.Partition, next, progress.End)
+			if err != nil {
+				return err
+			}
+			if pending {
+				return fmt.Errorf("stopped at offset %d of %d", next, progress.End)
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pendingRecords reports whether records a consumer would receive remain
+// between offset and end, rather than only transaction markers and offsets
+// removed by compaction
+func (c *Client) pendingRecords(topic string, partition int32, offset, end int64) (bool, error) {
+	for offset < end {
+		page, err := c.FetchBatches(topic, partition, offset, searchCheckMaxBytes)
+		if err != nil {
+			return false, err
+		}
+		if len(page.Batches) == 0 {
+			// nothing could be fetched although offsets remain
+			return true, nil
+		}
+		for _, batch := range page.Batches {
+			if batch.Control {
+				continue
+			}
+			for _, record := range batch.Records {
+				if record.Offset >= offset && record.Offset < end {
+					return true, nil
+				}
+			}
+		}
+		offset = page.Next
+	}
+	return false, nil
+}
+
+func (s *Search) finish(progress *PartitionProgress, err error) {
+	if err == context.Canceled {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	progress.Done = true
+	progress.Err = err
+}
+
+// Progress returns a snapshot of the search progress
+func (s *Search) Progress() SearchProgress {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot := SearchProgress{Done: true}
+	for _, progress := range s.partitions {
+		snapshot.Partitions = append(snapshot.Partitions, *progress)
+		snapshot.Scanned += progress.Scanned
+		snapshot.Matched += progress.Matched
+		snapshot.Total += progress.End - progress.Start
+		snapshot.Done = snapshot.Done && progress.Done
+	}
+
+	switch {
+	case s.started.IsZero():
+	case s.finished.IsZero():
+		snapshot.Elapsed = time.Since(s.started)
+	default:
+		snapshot.Elapsed = s.finished.Sub(s.started)
+	}
+	return snapshot
+}
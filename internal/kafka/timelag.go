@@ -0,0 +1,42 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// timeLagFetchBytes bounds the fetch of the record at a committed offset
+	timeLagFetchBytes = 64 * 1024
+	// maxTimeLagFetches caps the records fetched to measure time lags in a
+	// single consumer groups refresh, so topics with many partitions and
+	// groups do not cost a fetch per partition of every group
+	maxTimeLagFetches = 32
+)
+
+// RecordTimestamp returns the timestamp of the first record of a partition
+// at or after offset
+func (c *Client) RecordTimestamp(topic string, partition int32, offset int64) (time.Time, error) {
+	page, err := c.FetchBatches(topic, partition, offset, timeLagFetchBytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, batch := range page.Batches {
+		for _, record := range batch.Records {
+			if record.Offset >= offset {
+				return record.Timestamp, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("no record at offset %d of partition %d", offset, partition)
+}
+
+// timeLag estimates how far behind a consumer at committed is, from the
+// timestamp of the next record it will consume
+func (c *Client) timeLag(topic string, partition int32, committed int64, now time.Time) (time.Duration, bool) {
+	timestamp, err := c.RecordTimestamp(topic, partition, committed)
+	if err != nil {
+		return 0, false
+	}
+	return max(0, now.Sub(timestamp)), true
+}
@@ -0,0 +1,161 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// deleteRecordsTimeout bounds how long the brokers take to move the log
+// start offsets
+const deleteRecordsTimeout = 30 * time.Second
+
+// PartitionTruncate is the planned truncation of a partition: the records
+// from its log start offset up to Target, excluded, are deleted
+type PartitionTruncate struct {
+	Partition int32
+	LogStart  int64
+	Target    int64
+	End       int64
+	// LowWatermark is the log start offset after the truncation
+	LowWatermark int64
+	Err          error
+}
+
+// Removed returns how many offsets the truncation deletes
+func (p PartitionTruncate) Removed() int64 {
+	return max(0, p.Target-p.LogStart)
+}
+
+// ParsePartitions parses a list of partitions such as `0,2,5-7`, or `all`
+// for every partition in all
+func ParsePartitions(text string, all []int32) ([]int32, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.EqualFold(text, "all") {
+		return all, nil
+	}
+
+	exists := make(map[int32]bool, len(all))
+	for _, partition := range all {
+		exists[partition] = true
+	}
+
+	selected := make(map[int32]bool)
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.ParseInt(strings.TrimSpace(first), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition %q", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.ParseInt(strings.TrimSpace(last), 10, 32); err != nil || to < from {
+				return nil, fmt.Errorf("invalid partition range %q", part)
+			}
+		}
+		for p := from; p <= to; p++ {
+			if !exists[int32(p)] {
+				return nil, fmt.Errorf("partition %d does not exist", p)
+			}
+			selected[int32(p)] = true
+		}
+	}
+
+	partitions := make([]int32, 0, len(selected))
+	for partition := range selected {
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	return partitions, nil
+}
+
+// PlanTruncate resolves the offset every partition would be truncated to
+// for the position, a timestamp resolving to the first offset at or after it.
+// The log start and end offsets come from a single ListOffsets round.
+func (c *Client) PlanTruncate(topic string, partitions []int32, before Position) ([]PartitionTruncate, error) {
+	offsets, err := c.ListOffsets([]string{topic})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+
+	plan := make([]PartitionTruncate, 0, len(partitions))
+	for _, partition := range partitions {
+		partitionOffsets, ok := offsets[topic][partition]
+		if !ok {
+			return nil, fmt.Errorf("no offsets returned for partition %d", partition)
+		}
+		if partitionOffsets.Err != nil {
+			return nil, fmt.Errorf("failed to list offsets of partition %d: %w", partition, partitionOffsets.Err)
+		}
+		target, err := c.resolveBetween(topic, partition, before, partitionOffsets.Oldest, partitionOffsets.Newest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve offset of partition %d: %w", partition, err)
+		}
+
+		plan = append(plan, PartitionTruncate{
+			Partition: partition,
+			LogStart:  partitionOffsets.Oldest,
+			Target:    target,
+			End:       partitionOffsets.Newest,
+		})
+	}
+	return plan, nil
+}
+
+// Truncate deletes the records of the planned partitions before their
+// target offsets with DeleteRecords, sent to each partition's leader. The
+// plan is updated with the new low watermarks or per partition errors.
+func (c *Client) Truncate(topic string, plan []PartitionTruncate) error {
+	byLeader := make(map[*sarama.Broker][]*PartitionTruncate)
+	var failed int
+	for i := range plan {
+		p := &plan[i]
+		if p.Removed() == 0 {
+			p.LowWatermark = p.LogStart
+			continue
+		}
+		leader, err := c.Leader(topic, p.Partition)
+		if err != nil {
+			p.Err = err
+			failed++
+			continue
+		}
+		byLeader[leader] = append(byLeader[leader], p)
+	}
+
+	for leader, partitions := range byLeader {
+		offsets := make(map[int32]int64, len(partitions))
+		for _, p := range partitions {
+			offsets[p.Partition] = p.Target
+		}
+
+		response, err := leader.DeleteRecords(&sarama.DeleteRecordsRequest{
+			Topics:  map[string]*sarama.DeleteRecordsRequestTopic{topic: {PartitionOffsets: offsets}},
+			Timeout: deleteRecordsTimeout,
+		})
+		for _, p := range partitions {
+			switch {
+			case err != nil:
+				p.Err = err
+			case response.Topics[topic] == nil || response.Topics[topic].Partitions[p.Partition] == nil:
+				p.Err = fmt.Errorf("no result returned")
+			case response.Topics[topic].Partitions[p.Partition].Err != sarama.ErrNoError:
+				p.Err = response.Topics[topic].Partitions[p.Partition].Err
+			default:
+				p.LowWatermark = response.Topics[topic].Partitions[p.Partition].LowWatermark
+				continue
+			}
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d partitions could not be truncated", failed)
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+// Package lint checks the state of a cluster for risky configurations and
+// consumer group behaviors.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+)
+
+const (
+	// skewFactor is how much larger than the average a partition may be
+	skewFactor = 2
+	// skewMinRecords ignores the skew of topics too small to matter
+	skewMinRecords = 10000
+	// leaderShareFactor is how much more than its fair share of the
+	// leaders a broker may lead
+	leaderShareFactor = 1.5
+	// partialLagMin is the lag from which a partition is lagging
+	partialLagMin = 1000
+	// caughtUpMax is the lag under which a partition is caught up
+	caughtUpMax = 100
+	// retentionMargin is the share of the retention a group may be behind
+	retentionMargin = 0.8
+)
+
+// Severity ranks the results of the checks
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "Error"
+	case Warning:
+		return "Warning"
+	default:
+		return "Info"
+	}
+}
+
+// Result is a problem found by a check on a topic, a consumer group on a
+// topic, or the cluster when both are empty
+type Result struct {
+	Severity Severity
+	Check    string
+	Topic    string
+	Group    string
+	Message  string
+}
+
+// Object describes what the result is about
+func (r Result) Object() string {
+	switch {
+	case r.Group != "":
+		return fmt.Sprintf("group %s on %s", r.Group, r.Topic)
+	case r.Topic != "":
+		return "topic " + r.Topic
+	default:
+		return "cluster"
+	}
+}
+
+// Check finds a kind of problem in the state of a cluster
+type Check struct {
+	Name string
+	Run  func(state *kafka.ClusterState) []Result
+}
+
+// Checks are the checks Run runs
+var Checks = []Check{
+	{"single replica", checkSingleReplica},
+	{"single partition", checkSinglePartition},
+	{"min in-sync replicas", checkMinInSync},
+	{"partition skew", checkPartitionSkew},
+	{"preferred leaders", checkPreferredLeaders},
+	{"leader balance", checkLeaderBalance},
+	{"partial lag", checkPartialLag},
+	{"stale group", checkStaleGroups},
+	{"no consumers", checkNoConsumers},
+	{"retention", checkRetention},
+}
+
+// Run runs the checks against the state of a cluster and returns their
+// results, the most severe first
+func Run(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, check := range Checks {
+		for _, result := range check.Run(state) {
+			result.Check = check.Name
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Group < b.Group
+	})
+	return results
+}
+
+// sortedTopics returns the topics of the state sorted by name
+func sortedTopics(state *kafka.ClusterState) []*kafka.TopicState {
+	topics := make([]*kafka.TopicState, 0, len(state.Topics))
+	for _, topic := range state.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+	return topics
+}
+
+// internal tells whether a topic is managed by Kafka itself
+func internal(topic string) bool {
+	return strings.HasPrefix(topic, "__")
+}
+
+func checkSingleReplica(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, topic := range sortedTopics(state) {
+		if topic.ReplicationFactor() == 1 && len(state.Brokers) > 1 {
+			results = append(results, Result{
+				Severity: Warning,
+				Topic:    topic.Name,
+				Message:  "replication factor 1, a broker failure makes its partitions unavailable",
+			})
+		}
+	}
+	return results
+}
+
+func checkSinglePartition(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, topic := range sortedTopics(state) {
+		if len(topic.Partitions) == 1 && !internal(topic.Name) {
+			results = append(results, Result{
+				Severity: Info,
+				Topic:    topic.Name,
+				Message:  "single partition, it cannot be consumed in parallel",
+			})
+		}
+	}
+	return results
+}
+
+func checkMinInSync(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, topic := range sortedTopics(state) {
+		minInSync, ok := topic.ConfigInt(kafka.ConfigMinInSyncReplicas)
+		replicas := int64(topic.ReplicationFactor())
+		if !ok || replicas == 0 || minInSync < replicas {
+			continue
+		}
+		if minInSync > replicas {
+			results = append(results, Result{
+				Severity: Error,
+				Topic:    topic.Name,
+				Message:  fmt.Sprintf("min.insync.replicas %d exceeds the replication factor %d, acks=all produces always fail", minInSync, replicas),
+			})
+		} else if replicas > 1 {
+			results = append(results, Result{
+				Severity: Warning,
+				Topic:    topic.Name,
+				Message:  fmt.Sprintf("min.insync.replicas equals the replication factor %d, a single replica down blocks acks=all produces", replicas),
+			})
+		}
+	}
+	return results
+}
+
+func checkPartitionSkew(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, topic := range sortedTopics(state) {
+		if len(topic.Partitions) < 2 {
+			continue
+		}
+
+		var total, largest int64
+		var largestPartition int32
+		for _, partition := range topic.Partitions {
+			span := partition.Newest - partition.Oldest
+			total += span
+			if span > largest {
+				largest, largestPartition = span, partition.Partition
+			}
+		}
+		average := float64(total) / float64(len(topic.Partitions))
+		if total >= skewMinRecords && float64(largest) > skewFactor*average {
+			results = append(results, Result{
+				Severity: Info,
+				Topic:    topic.Name,
+				Message:  fmt.Sprintf("partition %d holds %.1fx the average partition, check the keys", largestPartition, float64(largest)/average),
+			})
+		}
+	}
+	return results
+}
+
+func checkPreferredLeaders(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, topic := range sortedTopics(state) {
+		var offline, moved []string
+		for _, partition := range topic.Partitions {
+			id := fmt.Sprint(partition.Partition)
+			switch {
+			case partition.Leader < 0:
+				offline = append(offline, id)
+			case len(partition.Replicas) > 0 && partition.Leader != partition.Replicas[0]:
+				moved = append(moved, id)
+			}
+		}
+		if len(offline) > 0 {
+			results = append(results, Result{
+				Severity: Error,
+				Topic:    topic.Name,
+				Message:  "no leader for partitions " + strings.Join(offline, ", "),
+			})
+		}
+		if len(moved) > 0 {
+			results = append(results, Result{
+				Severity: Warning,
+				Topic:    topic.Name,
+				Message:  "not led by their preferred replica: partitions " + strings.Join(moved, ", "),
+			})
+		}
+	}
+	return results
+}
+
+func checkLeaderBalance(state *kafka.ClusterState) []Result {
+	if len(state.Brokers) < 2 {
+		return nil
+	}
+
+	leaders := make(map[int32]int)
+	total := 0
+	for _, topic := range state.Topics {
+		for _, partition := range topic.Partitions {
+			if partition.Leader >= 0 {
+				leaders[partition.Leader]++
+				total++
+			}
+		}
+	}
+	if total < 2*len(state.Brokers) {
+		return nil
+	}
+
+	fair := float64(total) / float64(len(state.Brokers))
+	var results []Result
+	for _, broker := range state.Brokers {
+		if count := leaders[broker]; float64(count) > leaderShareFactor*fair {
+			results = append(results, Result{
+				Severity: Warning,
+				Message:  fmt.Sprintf("broker %d leads %d partitions, %.0f would be its fair share", broker, count, fair),
+			})
+		}
+	}
+	return results
+}
+
+// groupLags returns the lag of a group on each partition of a topic it
+// committed offsets on, by partition
+func groupLags(topic *kafka.TopicState, offsets map[int32]kafka.GroupPartition) map[int32]int64 {
+	lags := make(map[int32]int64)
+	for _, partition := range topic.Partitions {
+		if offset, ok := offsets[partition.Partition]; ok && partition.Err == nil {
+			lags[partition.Partition] = max(0, partition.Newest-offset.Committed)
+		}
+	}
+	return lags
+}
+
+// sortedPartitions returns the partitions of a set sorted
+func sortedPartitions(partitions []int32) string {
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+	ids := make([]string, len(partitions))
+	for i, partition := range partitions {
+		ids[i] = fmt.Sprint(partition)
+	}
+	return strings.Join(ids, ", ")
+}
+
+func checkPartialLag(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, group := range state.Groups {
+		for name, offsets := range group.Offsets {
+			topic, ok := state.Topics[name]
+			if !ok {
+				continue
+			}
+
+			var lagging []int32
+			var largest int64
+			caughtUp := false
+			for partition, lag := range groupLags(topic, offsets) {
+				if lag >= partialLagMin {
+					lagging = append(lagging, partition)
+					largest = max(largest, lag)
+				} else if lag < caughtUpMax {
+					caughtUp = true
+				}
+			}
+			if len(lagging) > 0 && caughtUp {
+				results = append(results, Result{
+					Severity: Warning,
+					Topic:    name,
+					Group:    group.ID,
+					Message:  fmt.Sprintf("lags only on partitions %s (up to %d records), a consumer or partition may be stuck", sortedPartitions(lagging), largest),
+				})
+			}
+		}
+	}
+	return results
+}
+
+func checkStaleGroups(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, group := range state.Groups {
+		if group.State != "Empty" {
+			continue
+		}
+		for name, offsets := range group.Offsets {
+			topic, ok := state.Topics[name]
+			if !ok {
+				continue
+			}
+
+			var lag int64
+			for _, partitionLag := range groupLags(topic, offsets) {
+				lag += partitionLag
+			}
+			if lag > 0 {
+				results = append(results, Result{
+					Severity: Info,
+					Topic:    name,
+					Group:    group.ID,
+					Message:  fmt.Sprintf("no members and %d records behind, the group may be abandoned", lag),
+				})
+			}
+		}
+	}
+	return results
+}
+
+func checkNoConsumers(state *kafka.ClusterState) []Result {
+	consumed := make(map[string]bool)
+	for _, group := range state.Groups {
+		for topic := range group.Subscribed {
+			consumed[topic] = true
+		}
+		for topic := range group.Offsets {
+			consumed[topic] = true
+		}
+	}
+
+	var results []Result
+	for _, topic := range sortedTopics(state) {
+		if consumed[topic.Name] || internal(topic.Name) {
+			continue
+		}
+		results = append(results, Result{
+			Severity: Info,
+			Topic:    topic.Name,
+			Message:  "no consumer group subscribes to it or committed offsets on it",
+		})
+	}
+	return results
+}
+
+func checkRetention(state *kafka.ClusterState) []Result {
+	var results []Result
+	for _, group := range state.Groups {
+		for name, offsets := range group.Offsets {
+			topic, ok := state.Topics[name]
+			if !ok {
+				continue
+			}
+
+			var expired []int32
+			var timeLag time.Duration
+			for _, partition := range topic.Partitions {
+				offset, ok := offsets[partition.Partition]
+				if !ok || partition.Err != nil {
+					continue
+				}
+				if offset.Committed < partition.Oldest {
+					expired = append(expired, partition.Partition)
+				}
+				if offset.TimeLagKnown {
+					timeLag = max(timeLag, offset.TimeLag)
+				}
+			}
+
+			if len(expired) > 0 {
+				results = append(results, Result{
+					Severity: Error,
+					Topic:    name,
+					Group:    group.ID,
+					Message:  fmt.Sprintf("records of partitions %s were deleted by retention before being consumed", sortedPartitions(expired)),
+				})
+				continue
+			}
+
+			retentionMs, ok := topic.ConfigInt(kafka.ConfigRetentionMs)
+			retention := time.Duration(retentionMs) * time.Millisecond
+			if ok && retention > 0 && timeLag > time.Duration(retentionMargin*float64(retention)) {
+				results = append(results, Result{
+					Severity: Warning,
+					Topic:    name,
+					Group:    group.ID,
+					Message:  fmt.Sprintf("%s behind with a retention of %s, records may be deleted before being consumed", timeLag.Round(time.Second), retention),
+				})
+			}
+		}
+	}
+	return results
+}
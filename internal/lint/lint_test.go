@@ -0,0 +1,127 @@
+package lint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+)
+
+// partition returns the state of a partition led by its first replica
+func partition(id int32, oldest, newest int64, replicas ...int32) kafka.PartitionState {
+	return kafka.PartitionState{
+		Partition:        id,
+		Leader:           replicas[0],
+		Replicas:         replicas,
+		InSync:           replicas,
+		PartitionOffsets: kafka.PartitionOffsets{Oldest: oldest, Newest: newest},
+	}
+}
+
+func testState() *kafka.ClusterState {
+	return &kafka.ClusterState{
+		Brokers: []int32{1, 2, 3},
+		Topics: map[string]*kafka.TopicState{
+			"orders": {
+				Name: "orders",
+				Partitions: []kafka.PartitionState{
+					partition(0, 0, 5000, 1, 2),
+					partition(1, 0, 5000, 2, 3),
+					partition(2, 0, 5000, 3, 1),
+				},
+				Config: map[string]string{kafka.ConfigMinInSyncReplicas: "2", kafka.ConfigRetentionMs: "3600000"},
+			},
+			"audit": {
+				Name:       "audit",
+				Partitions: []kafka.PartitionState{partition(0, 100, 200, 1)},
+				Config:     map[string]string{kafka.ConfigMinInSyncReplicas: "1", kafka.ConfigRetentionMs: "-1"},
+			},
+		},
+		Groups: []kafka.GroupState{{
+			ID:         "billing",
+			State:      "Stable",
+			Members:    2,
+			Subscribed: map[string]bool{"orders": true},
+			Offsets: map[string]map[int32]kafka.GroupPartition{
+				"orders": {
+					0: {Committed: 5000},
+					1: {Committed: 1000, TimeLag: 55 * time.Minute, TimeLagKnown: true},
+					2: {Committed: 4990},
+				},
+			},
+		}},
+	}
+}
+
+// find returns the result of a check on an object
+func find(results []Result, check, topic, group string) (Result, bool) {
+	for _, result := range results {
+		if result.Check == check && result.Topic == topic && result.Group == group {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+func TestRun(t *testing.T) {
+	results := Run(testState())
+
+	expected := []struct {
+		check, topic, group string
+		severity            Severity
+	}{
+		{"min in-sync replicas", "orders", "", Warning},
+		{"single replica", "audit", "", Warning},
+		{"single partition", "audit", "", Info},
+		{"no consumers", "audit", "", Info},
+		{"partial lag", "orders", "billing", Warning},
+		{"retention", "orders", "billing", Warning},
+	}
+	for _, want := range expected {
+		result, ok := find(results, want.check, want.topic, want.group)
+		if !ok {
+			t.Errorf("no %s result for %s/%s in %v", want.check, want.topic, want.group, results)
+			continue
+		}
+		if result.Severity != want.severity {
+			t.Errorf("%s of %s/%s is %v, want %v", want.check, want.topic, want.group, result.Severity, want.severity)
+		}
+	}
+	if len(results) != len(expected) {
+		t.Errorf("got %d results, want %d: %v", len(results), len(expected), results)
+	}
+
+	for i := 1; i < len(results); i++ {
+		if results[i].Severity > results[i-1].Severity {
+			t.Fatalf("results are not sorted by severity: %v", results)
+		}
+	}
+}
+
+func TestLeadersAndExpiredOffsets(t *testing.T) {
+	state := testState()
+	orders := state.Topics["orders"]
+	orders.Partitions[1].Leader = 3
+	orders.Partitions[2].Leader = -1
+	orders.Partitions[0].Oldest = 4000
+	state.Groups[0].Offsets["orders"][0] = kafka.GroupPartition{Committed: 3000}
+	state.Groups[0].State = "Empty"
+
+	results := Run(state)
+	if result, ok := find(results, "retention", "orders", "billing"); !ok || result.Severity != Error {
+		t.Errorf("expired offsets not reported as an error: %v", results)
+	}
+	if _, ok := find(results, "stale group", "orders", "billing"); !ok {
+		t.Errorf("empty group not reported: %v", results)
+	}
+
+	severities := make(map[Severity]int)
+	for _, result := range results {
+		if result.Check == "preferred leaders" {
+			severities[result.Severity]++
+		}
+	}
+	if severities[Error] != 1 || severities[Warning] != 1 {
+		t.Errorf("got %v preferred leader results, want an offline and a moved partition", severities)
+	}
+}
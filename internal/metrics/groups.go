@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/clemsau/kafe/internal/models"
+)
+
+// GroupPollInterval is how often the consumer groups of the tracked topics
+// are sampled
+const GroupPollInterval = 5 * time.Second
+
+// GroupFetcher returns the consumer groups of a topic
+type GroupFetcher func(topic string) ([]models.ConsumerGroupInfo, error)
+
+// GroupPoll is the result of the last poll of the consumer groups of a topic
+type GroupPoll struct {
+	Groups []models.ConsumerGroupInfo
+	Err    error
+	At     time.Time
+}
+
+// TrackGroups starts sampling the consumer groups of a topic. The topic
+// stays tracked until RemoveTopic, so its history keeps growing once the
+// page that asked for it is closed.
+func (s *Store) TrackGroups(topic string) {
+	s.mutex.Lock()
+	tracked := s.tracked[topic]
+	s.tracked[topic] = true
+	s.mutex.Unlock()
+
+	if !tracked {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Groups returns the last poll of the consumer groups of a topic, false
+// before the first one
+func (s *Store) Groups(topic string) (GroupPoll, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	poll, ok := s.polls[topic]
+	return poll, ok
+}
+
+// PollGroups samples the lag and committed offsets of the consumer groups
+// of the tracked topics every GroupPollInterval, and as soon as a topic is
+// tracked. It blocks until ctx is cancelled.
+func (s *Store) PollGroups(ctx context.Context, fetch GroupFetcher) {
+	ticker := time.NewTicker(GroupPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.pollGroups(fetch)
+
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Store) pollGroups(fetch GroupFetcher) {
+	s.mutex.RLock()
+	topics := make([]string, 0, len(s.tracked))
+	for topic := range s.tracked {
+		topics = append(topics, topic)
+	}
+	s.mutex.RUnlock()
+
+	for _, topic := range topics {
+		groups, err := fetch(topic)
+		for _, group := range groups {
+			s.Record(GroupKey(GroupLag, group.ID, group.Topic), group.LastUpdate, float64(group.TotalLag))
+			s.Record(GroupKey(GroupCommitted, group.ID, group.Topic), group.LastUpdate, float64(group.Committed))
+		}
+
+		s.mutex.Lock()
+		if s.tracked[topic] {
+			s.polls[topic] = GroupPoll{Groups: groups, Err: err, At: time.Now()}
+		}
+		s.mutex.Unlock()
+	}
+}
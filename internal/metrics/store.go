@@ -0,0 +1,265 @@
+// Package metrics keeps the recent history of the topic and consumer group
+// statistics, to compute rates and trends over time windows.
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is how much history a store keeps by default
+const DefaultWindow = time.Hour
+
+// Windows are the time windows the rates are usually queried over
+var Windows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// Kind identifies what a series measures
+type Kind string
+
+const (
+	// TopicOffsets is the sum of the high watermarks of a topic, a counter
+	// whose rate is the produce rate
+	TopicOffsets Kind = "topic-offsets"
+	// GroupCommitted is the sum of the offsets a group committed on a
+	// topic, a counter whose rate is the consume rate
+	GroupCommitted Kind = "group-committed"
+	// GroupLag is the total lag of a group on a topic, a gauge
+	GroupLag Kind = "group-lag"
+)
+
+// Key identifies a series. Group series are keyed by group and topic, topic
+// series by topic only.
+type Key struct {
+	Kind  Kind
+	Topic string
+	Group string
+}
+
+// TopicKey returns the key of a topic series
+func TopicKey(kind Kind, topic string) Key {
+	return Key{Kind: kind, Topic: topic}
+}
+
+// GroupKey returns the key of a consumer group series
+func GroupKey(kind Kind, group, topic string) Key {
+	return Key{Kind: kind, Topic: topic, Group: group}
+}
+
+// Sample is the value of a series at a time
+type Sample struct {
+	At    time.Time
+	Value float64
+}
+
+// Store keeps the samples of many series over a sliding time window. It is
+// safe for concurrent use.
+type Store struct {
+	mutex  sync.RWMutex
+	window time.Duration
+	series map[Key][]Sample
+
+	// tracked holds the topics whose consumer groups are polled, polls the
+	// result of their last poll, and wake starts a poll early
+	tracked map[string]bool
+	polls   map[string]GroupPoll
+	wake    chan struct{}
+}
+
+// NewStore creates a store keeping the samples of the last window, the
+// default window when window is not positive
+func NewStore(window time.Duration) *Store {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Store{
+		window:  window,
+		series:  make(map[Key][]Sample),
+		tracked: make(map[string]bool),
+		polls:   make(map[string]GroupPoll),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Window returns how much history the store keeps
+func (s *Store) Window() time.Duration {
+	return s.window
+}
+
+// Record appends a sample to a series and drops the samples that left the
+// window. Samples older than the last one of the series are ignored.
+func (s *Store) Record(key Key, at time.Time, value float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	samples := s.series[key]
+	if n := len(samples); n > 0 && !at.After(samples[n-1].At) {
+		return
+	}
+	samples = append(samples, Sample{At: at, Value: value})
+
+	cutoff := at.Add(-s.window)
+	drop := sort.Search(len(samples), func(i int) bool {
+		return samples[i].At.After(cutoff)
+	})
+	if drop > 0 {
+		samples = append(samples[:0], samples[drop:]...)
+	}
+	s.series[key] = samples
+}
+
+// RemoveTopic drops the series of a topic, and of the groups on it, and
+// stops polling its groups
+func (s *Store) RemoveTopic(topic string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.tracked, topic)
+	delete(s.polls, topic)
+
+	for key := range s.series {
+		if key.Topic == topic {
+			delete(s.series, key)
+		}
+	}
+}
+
+// RemoveGroup drops the series of a group on a topic
+func (s *Store) RemoveGroup(group, topic string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key := range s.series {
+		if key.Group == group && key.Topic == topic {
+			delete(s.series, key)
+		}
+	}
+}
+
+// Samples returns a copy of the samples of a series within the last window
+// before its latest sample, all of them when window is not positive
+func (s *Store) Samples(key Key, window time.Duration) []Sample {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	samples := s.within(key, window)
+	return append([]Sample(nil), samples...)
+}
+
+// Latest returns the last sample of a series
+func (s *Store) Latest(key Key) (Sample, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	samples := s.series[key]
+	if len(samples) == 0 {
+		return Sample{}, false
+	}
+	return samples[len(samples)-1], true
+}
+
+// Rate returns the per second increase of a counter series over the last
+// window, false when the window holds less than two samples. Decreases,
+// such as a topic recreated, count as resets rather than negative rates.
+func (s *Store) Rate(key Key, window time.Duration) (float64, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	samples := s.within(key, window)
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	var increase float64
+	for i := 1; i < len(samples); i++ {
+		if delta := samples[i].Value - samples[i-1].Value; delta > 0 {
+			increase += delta
+		}
+	}
+	elapsed := samples[len(samples)-1].At.Sub(samples[0].At).Seconds()
+	return increase / elapsed, true
+}
+
+// LastRate returns the per second increase of a counter series between its
+// last two samples
+func (s *Store) LastRate(key Key) (float64, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	samples := s.series[key]
+	if len(samples) < 2 {
+		return 0, false
+	}
+	previous, last := samples[len(samples)-2], samples[len(samples)-1]
+	return max(0, last.Value-previous.Value) / last.At.Sub(previous.At).Seconds(), true
+}
+
+// Rates returns the per second increase of a counter series between each
+// pair of consecutive samples over the last window, stamped with the time of
+// the later sample. Resets yield a zero rate.
+func (s *Store) Rates(key Key, window time.Duration) []Sample {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	samples := s.within(key, window)
+	if len(samples) < 2 {
+		return nil
+	}
+
+	rates := make([]Sample, 0, len(samples)-1)
+	for i := 1; i < len(samples); i++ {
+		previous, sample := samples[i-1], samples[i]
+		rates = append(rates, Sample{
+			At:    sample.At,
+			Value: max(0, sample.Value-previous.Value) / sample.At.Sub(previous.At).Seconds(),
+		})
+	}
+	return rates
+}
+
+// Average returns the mean of a gauge series over the last window
+func (s *Store) Average(key Key, window time.Duration) (float64, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	samples := s.within(key, window)
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	var sum float64
+	for _, sample := range samples {
+		sum += sample.Value
+	}
+	return sum / float64(len(samples)), true
+}
+
+// Trend returns the per second change of a gauge series over the last
+// window, from its first to its last sample, e.g. a growing lag is a
+// positive trend
+func (s *Store) Trend(key Key, window time.Duration) (float64, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	samples := s.within(key, window)
+	if len(samples) < 2 {
+		return 0, false
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	return (last.Value - first.Value) / last.At.Sub(first.At).Seconds(), true
+}
+
+// within returns the samples of a series in the last window before its
+// latest sample. The caller holds the lock.
+func (s *Store) within(key Key, window time.Duration) []Sample {
+	samples := s.series[key]
+	if window <= 0 || len(samples) == 0 {
+		return samples
+	}
+
+	cutoff := samples[len(samples)-1].At.Add(-window)
+	start := sort.Search(len(samples), func(i int) bool {
+		return !samples[i].At.Before(cutoff)
+	})
+	return samples[start:]
+}
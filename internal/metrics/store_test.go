@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/clemsau/kafe/internal/models"
+)
+
+func TestStoreRate(t *testing.T) {
+	store := NewStore(time.Hour)
+	key := TopicKey(TopicOffsets, "orders")
+	start := time.Now()
+
+	for i := 0; i <= 10; i++ {
+		store.Record(key, start.Add(time.Duration(i)*10*time.Second), float64(i*100))
+	}
+
+	if rate, ok := store.Rate(key, time.Minute); !ok || rate != 10 {
+		t.Errorf("rate over 1m is %v, %v, want 10", rate, ok)
+	}
+	if rate, ok := store.LastRate(key); !ok || rate != 10 {
+		t.Errorf("last rate is %v, %v, want 10", rate, ok)
+	}
+	if samples := store.Samples(key, time.Minute); len(samples) != 7 {
+		t.Errorf("got %d samples over 1m, want 7", len(samples))
+	}
+	rates := store.Rates(key, time.Minute)
+	if len(rates) != 6 || rates[0].Value != 10 {
+		t.Errorf("rates over 1m are %v, want 6 rates of 10", rates)
+	}
+}
+
+func TestStoreRateIgnoresResets(t *testing.T) {
+	store := NewStore(time.Hour)
+	key := TopicKey(TopicOffsets, "orders")
+	start := time.Now()
+
+	store.Record(key, start, 100)
+	store.Record(key, start.Add(time.Second), 0)
+	store.Record(key, start.Add(2*time.Second), 50)
+
+	if rate, _ := store.Rate(key, 0); rate != 25 {
+		t.Errorf("rate is %v, want 25", rate)
+	}
+}
+
+func TestStoreWindow(t *testing.T) {
+	store := NewStore(time.Minute)
+	key := GroupKey(GroupLag, "billing", "orders")
+	start := time.Now()
+
+	for i := 0; i < 10; i++ {
+		store.Record(key, start.Add(time.Duration(i)*20*time.Second), float64(i))
+	}
+	store.Record(key, start, 42) // out of order, ignored
+
+	samples := store.Samples(key, 0)
+	if len(samples) != 3 || samples[0].Value != 7 {
+		t.Fatalf("kept %v, want the samples of the last minute", samples)
+	}
+	if trend, ok := store.Trend(key, 0); !ok || trend != 0.05 {
+		t.Errorf("trend is %v, %v, want 0.05", trend, ok)
+	}
+	if average, ok := store.Average(key, 0); !ok || average != 8 {
+		t.Errorf("average is %v, %v, want 8", average, ok)
+	}
+
+	store.RemoveTopic("orders")
+	if _, ok := store.Latest(key); ok {
+		t.Error("group series kept after its topic was removed")
+	}
+}
+
+func TestStorePollGroups(t *testing.T) {
+	store := NewStore(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetch := func(topic string) ([]models.ConsumerGroupInfo, error) {
+		return []models.ConsumerGroupInfo{{ID: "billing", Topic: topic, TotalLag: 12, Committed: 30, LastUpdate: time.Now()}}, nil
+	}
+	go store.PollGroups(ctx, fetch)
+	store.TrackGroups("orders")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if poll, ok := store.Groups("orders"); ok {
+			if len(poll.Groups) != 1 || poll.Err != nil {
+				t.Fatalf("polled %v, %v, want the billing group", poll.Groups, poll.Err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("tracked topic not polled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if sample, ok := store.Latest(GroupKey(GroupLag, "billing", "orders")); !ok || sample.Value != 12 {
+		t.Errorf("latest lag is %v, %v, want 12", sample.Value, ok)
+	}
+
+	store.RemoveTopic("orders")
+	if _, ok := store.Groups("orders"); ok {
+		t.Error("groups of a removed topic kept")
+	}
+}
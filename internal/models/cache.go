@@ -1,25 +1,31 @@
 package models
 
-import "sort"
+import (
+	"sort"
+	"sync"
+	"time"
+)
 
-// Cache to store topic information
+// Cache to store topic information, safe for concurrent use
 type TopicCache struct {
-	topics           map[string]TopicInfo
-	previousMessages map[string]int64
-	order            []string
+	mutex  sync.RWMutex
+	topics map[string]TopicInfo
+	order  []string
 }
 
 // NewTopicCache creates a new TopicCache
 func NewTopicCache() *TopicCache {
 	return &TopicCache{
-		topics:           make(map[string]TopicInfo),
-		previousMessages: make(map[string]int64),
-		order:            make([]string, 0),
+		topics: make(map[string]TopicInfo),
+		order:  make([]string, 0),
 	}
 }
 
 // UpsertTopic updates or inserts a topic
 func (tc *TopicCache) UpsertTopic(info TopicInfo) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
 	if _, ok := tc.topics[info.Name]; !ok {
 		tc.order = append(tc.order, info.Name)
 		sort.Strings(tc.order)
@@ -29,33 +35,93 @@ func (tc *TopicCache) UpsertTopic(info TopicInfo) {
 
 // Get returns a topic by name if it exists
 func (tc *TopicCache) Get(name string) (TopicInfo, bool) {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+
 	info, exists := tc.topics[name]
 	return info, exists
 }
 
-// GetSortedTopics returns all topics in sorted order
+// GetSortedTopics returns a snapshot of all topics in sorted order
 func (tc *TopicCache) GetSortedTopics() []TopicInfo {
+	return tc.GetFilteredTopics(nil)
+}
+
+// GetFilteredTopics returns a snapshot of the topics kept by keep in sorted
+// order, all of them when keep is nil. keep is called with the cache
+// locked and must not use it.
+func (tc *TopicCache) GetFilteredTopics(keep func(TopicInfo) bool) []TopicInfo {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+
 	topics := make([]TopicInfo, 0, len(tc.order))
 	for _, topicName := range tc.order {
-		if info, exists := tc.topics[topicName]; exists {
+		if info, exists := tc.topics[topicName]; exists && (keep == nil || keep(info)) {
 			topics = append(topics, info)
 		}
 	}
 	return topics
 }
 
-// GetOrder returns the order of topics
+// GetOrder returns a snapshot of the order of topics
 func (tc *TopicCache) GetOrder() []string {
-	return tc.order
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+
+	return append([]string(nil), tc.order...)
 }
 
-// GetPreviousMessages returns the previous message count for a topic
-func (tc *TopicCache) GetPreviousMessages(topic string) (int64, bool) {
-	count, exists := tc.previousMessages[topic]
-	return count, exists
+// Reconcile aligns the cache with the topics listed on the cluster. Cached
+// topics missing from names are marked Deleted, then evicted at the next
+// reconciliation. A Deleted topic listed again is kept with a zero
+// LastUpdate so it is fetched anew. It returns the evicted topics.
+func (tc *TopicCache) Reconcile(names []string) []string {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	listed := make(map[string]bool, len(names))
+	for _, name := range names {
+		listed[name] = true
+	}
+
+	var evicted []string
+	order := tc.order[:0]
+	for _, name := range tc.order {
+		info := tc.topics[name]
+		switch {
+		case listed[name] && info.Status == StatusDeleted:
+			info.Status = ""
+			info.LastUpdate = time.Time{}
+			tc.topics[name] = info
+		case listed[name]:
+		case info.Status == StatusDeleted:
+			delete(tc.topics, name)
+			evicted = append(evicted, name)
+			continue
+		default:
+			info.Status = StatusDeleted
+			info.Throughput = 0
+			tc.topics[name] = info
+		}
+		order = append(order, name)
+	}
+	tc.order = order
+	return evicted
 }
 
-// SetPreviousMessages sets the previous message count for a topic
-func (tc *TopicCache) SetPreviousMessages(topic string, count int64) {
-	tc.previousMessages[topic] = count
+// Remove evicts a topic
+func (tc *TopicCache) Remove(name string) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if _, ok := tc.topics[name]; !ok {
+		return
+	}
+	delete(tc.topics, name)
+	for i, topic := range tc.order {
+		if topic == name {
+			tc.order = append(tc.order[:i], tc.order[i+1:]...)
+			break
+		}
+	}
 }
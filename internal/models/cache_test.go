@@ -0,0 +1,146 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTopicCacheConcurrentAccess(t *testing.T) {
+	cache := NewTopicCache()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				cache.UpsertTopic(TopicInfo{Name: fmt.Sprintf("topic-%d", i%50), Messages: int64(i)})
+			}
+		}()
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				cache.GetSortedTopics()
+				cache.GetOrder()
+				cache.GetFilteredTopics(func(info TopicInfo) bool { return info.Messages%2 == 0 })
+				cache.Get("topic-1")
+			}
+		}()
+	}
+	wg.Wait()
+
+	topics := cache.GetSortedTopics()
+	if len(topics) != 50 {
+		t.Fatalf("got %d topics, want 50", len(topics))
+	}
+	for i := 1; i < len(topics); i++ {
+		if topics[i-1].Name >= topics[i].Name {
+			t.Fatalf("topics not sorted: %s before %s", topics[i-1].Name, topics[i].Name)
+		}
+	}
+}
+
+func TestTopicCacheSnapshotsAreIndependent(t *testing.T) {
+	cache := NewTopicCache()
+	cache.UpsertTopic(TopicInfo{Name: "b"})
+
+	order := cache.GetOrder()
+	topics := cache.GetSortedTopics()
+	cache.UpsertTopic(TopicInfo{Name: "a"})
+
+	if len(order) != 1 || order[0] != "b" {
+		t.Errorf("order snapshot changed to %v", order)
+	}
+	if len(topics) != 1 || topics[0].Name != "b" {
+		t.Errorf("topics snapshot changed to %v", topics)
+	}
+}
+
+func TestConsumerGroupCacheConcurrentAccess(t *testing.T) {
+	cache := NewConsumerGroupCache()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				cache.UpsertGroup(ConsumerGroupInfo{ID: fmt.Sprintf("group-%d", i%20), TotalLag: int64(i)})
+			}
+		}()
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				cache.GetSortedGroups()
+				cache.Get("group-1")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if groups := cache.GetSortedGroups(); len(groups) != 20 {
+		t.Fatalf("got %d groups, want 20", len(groups))
+	}
+}
+
+func TestTopicCacheReconcile(t *testing.T) {
+	cache := NewTopicCache()
+	cache.UpsertTopic(TopicInfo{Name: "kept", Status: "Ready"})
+	cache.UpsertTopic(TopicInfo{Name: "gone", Status: "Ready", Throughput: 3})
+
+	if evicted := cache.Reconcile([]string{"kept"}); len(evicted) != 0 {
+		t.Fatalf("evicted %v on the first missing listing", evicted)
+	}
+	info, ok := cache.Get("gone")
+	if !ok || info.Status != StatusDeleted || info.Throughput != 0 {
+		t.Fatalf("got %+v, want a Deleted topic", info)
+	}
+
+	evicted := cache.Reconcile([]string{"kept"})
+	if len(evicted) != 1 || evicted[0] != "gone" {
+		t.Fatalf("evicted %v, want [gone]", evicted)
+	}
+	if _, ok := cache.Get("gone"); ok {
+		t.Error("gone still cached")
+	}
+	if order := cache.GetOrder(); len(order) != 1 || order[0] != "kept" {
+		t.Errorf("order is %v", order)
+	}
+}
+
+func TestTopicCacheReconcileRecreated(t *testing.T) {
+	cache := NewTopicCache()
+	cache.UpsertTopic(TopicInfo{Name: "orders", Status: "Ready", LastUpdate: time.Now()})
+	cache.Reconcile(nil)
+	cache.Reconcile([]string{"orders"})
+
+	info, ok := cache.Get("orders")
+	if !ok || info.Status == StatusDeleted || !info.LastUpdate.IsZero() {
+		t.Fatalf("got %+v, want a topic to fetch again", info)
+	}
+}
+
+func TestConsumerGroupCacheReconcile(t *testing.T) {
+	cache := NewConsumerGroupCache()
+	cache.UpsertGroup(ConsumerGroupInfo{ID: "a", Status: "Active"})
+	cache.UpsertGroup(ConsumerGroupInfo{ID: "b", Status: "Active"})
+
+	cache.Reconcile([]string{"a"})
+	if info, _ := cache.Get("b"); info.Status != StatusDeleted {
+		t.Fatalf("b status is %q, want Deleted", info.Status)
+	}
+	if evicted := cache.Reconcile([]string{"a"}); len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("evicted %v, want [b]", evicted)
+	}
+	if groups := cache.GetSortedGroups(); len(groups) != 1 || groups[0].ID != "a" {
+		t.Errorf("groups are %v", groups)
+	}
+}
@@ -1,18 +1,45 @@
 package models
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 type ConsumerGroupInfo struct {
-	ID         string
-	Topic      string
-	Members    int
-	TotalLag   int64
-	Status     string
-	LastUpdate time.Time
+	ID       string
+	Topic    string
+	Members  int
+	TotalLag int64
+	// Committed sums the offsets committed on the partitions of the topic
+	Committed  int64
+	Partitions []PartitionLag
+	// TimeLag is the largest time lag of the partitions, a lower bound
+	// unless TimeLagKnown
+	TimeLag      time.Duration
+	TimeLagKnown bool
+	Status       string
+	LastUpdate   time.Time
 }
 
-// Cache to store consumer group information
+// PartitionLag is the lag of a consumer group on a partition
+type PartitionLag struct {
+	Partition int32
+	Committed int64
+	Newest    int64
+	// TimeLag is the age of the next record to consume, when TimeLagKnown
+	TimeLag      time.Duration
+	TimeLagKnown bool
+}
+
+// Lag returns the number of records left to consume, 0 when the committed
+// offset is ahead of the newest one, e.g. after the partition was truncated
+func (l PartitionLag) Lag() int64 {
+	return max(0, l.Newest-l.Committed)
+}
+
+// Cache to store consumer group information, safe for concurrent use
 type ConsumerGroupCache struct {
+	mutex  sync.RWMutex
 	groups map[string]ConsumerGroupInfo
 	order  []string
 }
@@ -25,6 +52,9 @@ func NewConsumerGroupCache() *ConsumerGroupCache {
 }
 
 func (c *ConsumerGroupCache) UpsertGroup(info ConsumerGroupInfo) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	if _, exists := c.groups[info.ID]; !exists {
 		c.order = append(c.order, info.ID)
 	}
@@ -32,11 +62,18 @@ func (c *ConsumerGroupCache) UpsertGroup(info ConsumerGroupInfo) {
 }
 
 func (c *ConsumerGroupCache) Get(id string) (ConsumerGroupInfo, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	info, exists := c.groups[id]
 	return info, exists
 }
 
+// GetSortedGroups returns a snapshot of the groups in insertion order
 func (c *ConsumerGroupCache) GetSortedGroups() []ConsumerGroupInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	groups := make([]ConsumerGroupInfo, 0, len(c.order))
 	for _, id := range c.order {
 		if info, exists := c.groups[id]; exists {
@@ -45,3 +82,35 @@ func (c *ConsumerGroupCache) GetSortedGroups() []ConsumerGroupInfo {
 	}
 	return groups
 }
+
+// Reconcile aligns the cache with the groups currently listed. Cached
+// groups missing from ids are marked Deleted, then evicted at the next
+// reconciliation. It returns the evicted groups.
+func (c *ConsumerGroupCache) Reconcile(ids []string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	listed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+	}
+
+	var evicted []string
+	order := c.order[:0]
+	for _, id := range c.order {
+		info := c.groups[id]
+		switch {
+		case listed[id]:
+		case info.Status == StatusDeleted:
+			delete(c.groups, id)
+			evicted = append(evicted, id)
+			continue
+		default:
+			info.Status = StatusDeleted
+			c.groups[id] = info
+		}
+		order = append(order, id)
+	}
+	c.order = order
+	return evicted
+}
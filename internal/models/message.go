@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// MessageHeader is a single record header
+type MessageHeader struct {
+	Key   string
+	Value []byte
+}
+
+// Message is a record read from a topic partition
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+	Headers   []MessageHeader
+
+	// DecodedKey and DecodedValue hold the JSON rendering of a key or value
+	// decoded from a binary format such as Avro
+	DecodedKey   *string
+	DecodedValue *string
+	// DecodeErr reports why the key or value could not be decoded
+	DecodeErr error
+	// Aborted is set on records of aborted transactions, which are only
+	// returned to consumers reading uncommitted data
+	Aborted bool
+}
+
+// KeyText returns the key as displayed and filtered
+func (m *Message) KeyText() string {
+	if m.DecodedKey != nil {
+		return *m.DecodedKey
+	}
+	return string(m.Key)
+}
+
+// ValueText returns the value as displayed and filtered
+func (m *Message) ValueText() string {
+	if m.DecodedValue != nil {
+		return *m.DecodedValue
+	}
+	return string(m.Value)
+}
+
+// Header returns the value of the first header with the given key
+func (m *Message) Header(key string) (string, bool) {
+	for _, header := range m.Headers {
+		if header.Key == key {
+			return string(header.Value), true
+		}
+	}
+	return "", false
+}
+
+// Size returns the number of bytes a message accounts for
+func (m *Message) Size() int {
+	size := len(m.Key) + len(m.Value)
+	for _, header := range m.Headers {
+		size += len(header.Key) + len(header.Value)
+	}
+	return size
+}
@@ -0,0 +1,90 @@
+package models
+
+import "sync"
+
+// MessageBuffer is a ring buffer of messages bounded by a number of
+// messages and, optionally, by the total size of their keys, values and
+// headers. The oldest messages are dropped to make room for new ones.
+type MessageBuffer struct {
+	mutex       sync.Mutex
+	messages    []Message
+	start       int
+	count       int
+	bytes       int
+	maxMessages int
+	maxBytes    int
+	dropped     int64
+}
+
+// NewMessageBuffer creates a buffer holding up to maxMessages messages and
+// maxBytes bytes, a maxBytes of 0 meaning no size limit
+func NewMessageBuffer(maxMessages, maxBytes int) *MessageBuffer {
+	if maxMessages < 1 {
+		maxMessages = 1
+	}
+	return &MessageBuffer{
+		messages:    make([]Message, maxMessages),
+		maxMessages: maxMessages,
+		maxBytes:    maxBytes,
+	}
+}
+
+// Add appends a message, dropping the oldest ones if the buffer is full
+func (b *MessageBuffer) Add(msg Message) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	size := msg.Size()
+	for b.count > 0 && (b.count == b.maxMessages || (b.maxBytes > 0 && b.bytes+size > b.maxBytes)) {
+		b.dropOldest()
+	}
+
+	b.messages[(b.start+b.count)%b.maxMessages] = msg
+	b.count++
+	b.bytes += size
+}
+
+func (b *MessageBuffer) dropOldest() {
+	b.bytes -= b.messages[b.start].Size()
+	b.messages[b.start] = Message{}
+	b.start = (b.start + 1) % b.maxMessages
+	b.count--
+	b.dropped++
+}
+
+// Since returns a copy of the buffered messages added after the first n
+// ones, oldest first, with how many messages were dropped and added since
+// the buffer was created. Since(0) returns all the buffered messages.
+func (b *MessageBuffer) Since(n int64) (messages []Message, dropped, added int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	added = b.dropped + int64(b.count)
+	skip := 0
+	if n > b.dropped {
+		skip = int(n - b.dropped)
+	}
+	for i := skip; i < b.count; i++ {
+		messages = append(messages, b.messages[(b.start+i)%b.maxMessages])
+	}
+	return messages, b.dropped, added
+}
+
+// Update applies fn to every buffered message, oldest first. fn must not
+// change the size of the messages.
+func (b *MessageBuffer) Update(fn func(msg *Message)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i := 0; i < b.count; i++ {
+		fn(&b.messages[(b.start+i)%b.maxMessages])
+	}
+}
+
+// Stats returns the number of buffered messages, their size in bytes and
+// how many messages were dropped since the buffer was created
+func (b *MessageBuffer) Stats() (count int, bytes int, dropped int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.count, b.bytes, b.dropped
+}
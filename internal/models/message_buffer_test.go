@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestMessageBufferSince(t *testing.T) {
+	buffer := NewMessageBuffer(3, 0)
+	for offset := int64(0); offset < 5; offset++ {
+		buffer.Add(Message{Offset: offset})
+	}
+
+	tests := []struct {
+		n    int64
+		want []int64
+	}{
+		{0, []int64{2, 3, 4}},
+		{3, []int64{3, 4}},
+		{4, []int64{4}},
+		{5, nil},
+	}
+	for _, test := range tests {
+		messages, dropped, added := buffer.Since(test.n)
+		if dropped != 2 || added != 5 {
+			t.Errorf("Since(%d) dropped %d and added %d, want 2 and 5", test.n, dropped, added)
+		}
+		var offsets []int64
+		for _, msg := range messages {
+			offsets = append(offsets, msg.Offset)
+		}
+		if len(offsets) != len(test.want) {
+			t.Errorf("Since(%d) = %v, want %v", test.n, offsets, test.want)
+			continue
+		}
+		for i := range offsets {
+			if offsets[i] != test.want[i] {
+				t.Errorf("Since(%d) = %v, want %v", test.n, offsets, test.want)
+				break
+			}
+		}
+	}
+}
@@ -2,12 +2,28 @@ package models
 
 import "time"
 
+// StatusDeleted is the status of a topic or consumer group that disappeared
+// from the cluster, shown for one refresh before it is evicted
+const StatusDeleted = "Deleted"
+
 type TopicInfo struct {
 	Name       string
 	Partitions int
 	Replicas   int
 	Status     string
-	Messages   int64
-	Throughput float64
-	LastUpdate time.Time
+	// Messages is the offset span of the partitions, newest minus oldest
+	// offset. It overcounts the records of compacted topics and of topics
+	// with transaction markers.
+	Messages int64
+	// HighWatermark and LastStable sum the high watermarks and last stable
+	// offsets of the partitions
+	HighWatermark int64
+	LastStable    int64
+	Throughput    float64
+	// UnderReplicated counts the partitions missing in-sync replicas and
+	// MinInSync is the fewest in-sync replicas of a partition, -1 when
+	// unknown
+	UnderReplicated int
+	MinInSync       int
+	LastUpdate      time.Time
 }
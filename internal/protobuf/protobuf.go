@@ -0,0 +1,185 @@
+// Package protobuf compiles protobuf schemas, from .proto sources or
+// descriptor sets, and decodes protobuf messages to JSON text.
+package protobuf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Files holds compiled file descriptors and looks messages up by name
+type Files struct {
+	files *protoregistry.Files
+}
+
+// Compile compiles .proto files, resolving imports in importPaths and among
+// the well-known types
+func Compile(importPaths []string, files ...string) (*Files, error) {
+	resolver := &protocompile.SourceResolver{ImportPaths: importPaths}
+	return compile(resolver, files...)
+}
+
+// CompileSources compiles .proto sources held in memory, keyed by file name
+func CompileSources(sources map[string]string, files ...string) (*Files, error) {
+	resolver := &protocompile.SourceResolver{Accessor: protocompile.SourceAccessorFromMap(sources)}
+	return compile(resolver, files...)
+}
+
+func compile(resolver protocompile.Resolver, files ...string) (*Files, error) {
+	compiler := protocompile.Compiler{Resolver: protocompile.WithStandardImports(resolver)}
+	compiled, err := compiler.Compile(context.Background(), files...)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := new(protoregistry.Files)
+	for _, file := range compiled {
+		if err := registerFile(registry, file); err != nil {
+			return nil, err
+		}
+	}
+	return &Files{files: registry}, nil
+}
+
+// registerFile registers a file after its imports
+func registerFile(registry *protoregistry.Files, file protoreflect.FileDescriptor) error {
+	if _, err := registry.FindFileByPath(file.Path()); err == nil {
+		return nil
+	}
+	imports := file.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFile(registry, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return registry.RegisterFile(file)
+}
+
+// LoadDescriptorSet loads a FileDescriptorSet, as written by
+// protoc --descriptor_set_out --include_imports
+func LoadDescriptorSet(path string) (*Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", path, err)
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", path, err)
+	}
+	return &Files{files: files}, nil
+}
+
+// Message returns the descriptor of a message by its full name
+func (f *Files) Message(name string) (protoreflect.MessageDescriptor, error) {
+	desc, err := f.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown message %s", name)
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", name)
+	}
+	return md, nil
+}
+
+// File returns the descriptor of a compiled file by its path
+func (f *Files) File(path string) (protoreflect.FileDescriptor, error) {
+	return f.files.FindFileByPath(path)
+}
+
+// MessageAt returns the message at the given indexes in a file: the first
+// index selects a top-level message, the following ones nested messages
+func MessageAt(file protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	if len(indexes) == 0 {
+		indexes = []int{0}
+	}
+
+	messages := file.Messages()
+	var md protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index < 0 || index >= messages.Len() {
+			return nil, fmt.Errorf("no message at index %v in %s", indexes, file.Path())
+		}
+		md = messages.Get(index)
+		messages = md.Messages()
+	}
+	return md, nil
+}
+
+// ParseMessageIndexes reads the message indexes prefixing a protobuf
+// payload in the schema registry wire format: a zigzag varint count
+// followed by as many zigzag varint indexes, a single 0 standing for the
+// first message
+func ParseMessageIndexes(data []byte) ([]int, []byte, error) {
+	count, n := consumeZigZag(data)
+	if n < 0 {
+		return nil, nil, errors.New("invalid message indexes")
+	}
+	data = data[n:]
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+	if count < 0 || count > int64(len(data)) {
+		return nil, nil, fmt.Errorf("invalid message index count %d", count)
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := consumeZigZag(data)
+		if n < 0 {
+			return nil, nil, errors.New("invalid message indexes")
+		}
+		indexes[i] = int(index)
+		data = data[n:]
+	}
+	return indexes, data, nil
+}
+
+func consumeZigZag(data []byte) (int64, int) {
+	v, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		return 0, n
+	}
+	return protowire.DecodeZigZag(v), n
+}
+
+// Decode unmarshals a message of the given type and returns it as compact
+// JSON text
+func Decode(md protoreflect.MessageDescriptor, data []byte) (string, error) {
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return "", fmt.Errorf("protobuf decode %s: %w", md.FullName(), err)
+	}
+
+	text, err := protojson.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("protobuf to json: %w", err)
+	}
+
+	// protojson randomizes its whitespace, compact it for stable output
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, text); err != nil {
+		return "", err
+	}
+	return compacted.String(), nil
+}
@@ -0,0 +1,156 @@
+package protobuf
+
+import (
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var sources = map[string]string{
+	"shop.proto": `syntax = "proto3";
+package shop;
+import "common.proto";
+
+message Order {
+  message Line {
+    string sku = 1;
+    int32 qty = 2;
+  }
+  string id = 1;
+  repeated Line lines = 2;
+  common.Money total = 3;
+}
+
+message Refund {
+  string order_id = 1;
+}
+`,
+	"common.proto": `syntax = "proto3";
+package common;
+
+message Money {
+  int64 cents = 1;
+  string currency = 2;
+}
+`,
+}
+
+func compileShop(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+	files, err := CompileSources(sources, "shop.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := files.File("shop.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// encode builds a message of the given type from its JSON form
+func encode(t *testing.T, md protoreflect.MessageDescriptor, text string) []byte {
+	t.Helper()
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal([]byte(text), msg); err != nil {
+		t.Fatal(err)
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseMessageIndexes(t *testing.T) {
+	tests := []struct {
+		data    []byte
+		want    []int
+		rest    string
+		wantErr bool
+	}{
+		// a single 0 stands for the first message
+		{data: []byte{0, 'x'}, want: []int{0}, rest: "x"},
+		// zigzag varints: the count, then the indexes
+		{data: []byte{2, 2, 'x'}, want: []int{1}, rest: "x"},
+		{data: []byte{4, 0, 4}, want: []int{0, 2}},
+		{data: []byte{2, 0xac, 0x02}, want: []int{150}},
+		{data: nil, wantErr: true},
+		{data: []byte{1}, wantErr: true},
+		{data: []byte{6, 0}, wantErr: true},
+		{data: []byte{2, 0x80}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		indexes, rest, err := ParseMessageIndexes(test.data)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseMessageIndexes(%v) = %v, want an error", test.data, indexes)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(indexes, test.want) || string(rest) != test.rest {
+			t.Errorf("ParseMessageIndexes(%v) = %v, %q, %v, want %v, %q", test.data, indexes, rest, err, test.want, test.rest)
+		}
+	}
+}
+
+func TestMessageAt(t *testing.T) {
+	file := compileShop(t)
+
+	tests := []struct {
+		indexes []int
+		want    string
+	}{
+		{nil, "shop.Order"},
+		{[]int{0}, "shop.Order"},
+		{[]int{1}, "shop.Refund"},
+		{[]int{0, 0}, "shop.Order.Line"},
+		{[]int{2}, ""},
+		{[]int{1, 0}, ""},
+		{[]int{-1}, ""},
+	}
+
+	for _, test := range tests {
+		md, err := MessageAt(file, test.indexes)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("MessageAt(%v) = %s, want an error", test.indexes, md.FullName())
+			}
+			continue
+		}
+		if err != nil || string(md.FullName()) != test.want {
+			t.Errorf("MessageAt(%v) = %v, %v, want %s", test.indexes, md, err, test.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	file := compileShop(t)
+	order := file.Messages().ByName("Order")
+
+	tests := []struct {
+		md   protoreflect.MessageDescriptor
+		text string
+	}{
+		{order, `{"id":"order-42","lines":[{"sku":"A-1","qty":3}],"total":{"cents":"1250","currency":"EUR"}}`},
+		{order.Messages().ByName("Line"), `{"sku":"B-2"}`},
+		{file.Messages().ByName("Refund"), `{"orderId":"order-42"}`},
+		{order, `{}`},
+	}
+
+	for _, test := range tests {
+		text, err := Decode(test.md, encode(t, test.md, test.text))
+		if err != nil || text != test.text {
+			t.Errorf("Decode(%s) = %s, %v, want %s", test.md.FullName(), text, err, test.text)
+		}
+	}
+
+	if _, err := Decode(order, []byte{0x0a, 0x05, 'x'}); err == nil {
+		t.Error("Decode of a truncated message: got no error")
+	}
+}
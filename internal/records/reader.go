@@ -0,0 +1,253 @@
+// Package records reads and writes Kafka records from and to files.
+package records
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/models"
+)
+
+// Format is a record file format
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+)
+
+// maxLineSize bounds a single JSON line
+const maxLineSize = 16 * 1024 * 1024
+
+// ParseFormat parses a format name, falling back to the file extension when name is empty
+func ParseFormat(name, path string) (Format, error) {
+	if name == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".jsonl", ".ndjson", ".json":
+			return FormatJSONL, nil
+		case ".csv":
+			return FormatCSV, nil
+		}
+		return "", fmt.Errorf("cannot guess the format of %q, set it explicitly", path)
+	}
+
+	switch Format(strings.ToLower(name)) {
+	case FormatJSONL, "json", "ndjson":
+		return FormatJSONL, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatRaw:
+		return FormatRaw, nil
+	}
+	return "", fmt.Errorf("unknown format %q", name)
+}
+
+// jsonRecord is a record as written in JSON lines. The value may be any
+// JSON document, strings are used verbatim and other documents are
+// produced in their compact form, a null value is produced as a tombstone.
+// Binary keys and values use the base64 fields.
+type jsonRecord struct {
+	Key         *string           `json:"key,omitempty"`
+	KeyBase64   *string           `json:"key_base64,omitempty"`
+	Value       json.RawMessage   `json:"value,omitempty"`
+	ValueBase64 *string           `json:"value_base64,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Partition   *int32            `json:"partition,omitempty"`
+}
+
+// Reader reads records one at a time from a JSON lines or CSV source
+type Reader struct {
+	format  Format
+	lines   *bufio.Scanner
+	csv     *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+// NewReader creates a reader for the format. CSV sources must start with a
+// header row naming the key, value, headers and partition columns.
+func NewReader(r io.Reader, format Format) (*Reader, error) {
+	reader := &Reader{format: format}
+
+	switch format {
+	case FormatJSONL:
+		reader.lines = bufio.NewScanner(r)
+		reader.lines.Buffer(make([]byte, 64*1024), maxLineSize)
+	case FormatCSV:
+		reader.csv = csv.NewReader(r)
+		reader.csv.FieldsPerRecord = -1
+		header, err := reader.csv.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		reader.line = 1
+		reader.columns = make(map[string]int)
+		for i, name := range header {
+			reader.columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		if _, ok := reader.columns["value"]; !ok {
+			return nil, errors.New("CSV header has no value column")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	return reader, nil
+}
+
+// Next returns the next record, io.EOF at the end of the source, or a
+// *kafka.RecordError when a single record is malformed
+func (r *Reader) Next() (kafka.BulkRecord, error) {
+	if r.format == FormatCSV {
+		return r.nextCSV()
+	}
+	return r.nextJSON()
+}
+
+func (r *Reader) nextJSON() (kafka.BulkRecord, error) {
+	for r.lines.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var raw jsonRecord
+		if err := json.Unmarshal(line, &raw); err != nil {
+			return kafka.BulkRecord{}, &kafka.RecordError{Line: r.line, Err: fmt.Errorf("invalid JSON: %w", err)}
+		}
+
+		record, err := raw.toRecord(r.line)
+		if err != nil {
+			return kafka.BulkRecord{}, &kafka.RecordError{Line: r.line, Err: err}
+		}
+		return record, nil
+	}
+
+	if err := r.lines.Err(); err != nil {
+		return kafka.BulkRecord{}, err
+	}
+	return kafka.BulkRecord{}, io.EOF
+}
+
+func (raw jsonRecord) toRecord(line int) (kafka.BulkRecord, error) {
+	record := kafka.BulkRecord{Line: line, Partition: kafka.AnyPartition}
+
+	switch {
+	case raw.KeyBase64 != nil:
+		key, err := base64.StdEncoding.DecodeString(*raw.KeyBase64)
+		if err != nil {
+			return record, fmt.Errorf("invalid key_base64: %w", err)
+		}
+		record.Message.Key = key
+	case raw.Key != nil:
+		record.Message.Key = []byte(*raw.Key)
+	}
+
+	switch {
+	case raw.ValueBase64 != nil:
+		value, err := base64.StdEncoding.DecodeString(*raw.ValueBase64)
+		if err != nil {
+			return record, fmt.Errorf("invalid value_base64: %w", err)
+		}
+		record.Message.Value = value
+	case bytes.Equal(raw.Value, []byte("null")):
+		// a tombstone, the value stays nil
+	case len(raw.Value) > 0:
+		var text string
+		if err := json.Unmarshal(raw.Value, &text); err == nil {
+			record.Message.Value = []byte(text)
+		} else {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw.Value); err != nil {
+				return record, fmt.Errorf("invalid value: %w", err)
+			}
+			record.Message.Value = compact.Bytes()
+		}
+	}
+
+	record.Message.Headers = headersFromMap(raw.Headers)
+	if raw.Partition != nil {
+		if *raw.Partition < 0 {
+			return record, fmt.Errorf("invalid partition %d", *raw.Partition)
+		}
+		record.Partition = *raw.Partition
+	}
+	return record, nil
+}
+
+func (r *Reader) nextCSV() (kafka.BulkRecord, error) {
+	row, err := r.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return kafka.BulkRecord{}, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return kafka.BulkRecord{}, &kafka.RecordError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		return kafka.BulkRecord{}, err
+	}
+	r.line, _ = r.csv.FieldPos(0)
+
+	column := func(name string) (string, bool) {
+		i, ok := r.columns[name]
+		if !ok || i >= len(row) {
+			return "", false
+		}
+		return row[i], true
+	}
+
+	record := kafka.BulkRecord{Line: r.line, Partition: kafka.AnyPartition}
+	if key, ok := column("key"); ok && key != "" {
+		record.Message.Key = []byte(key)
+	}
+	value, _ := column("value")
+	record.Message.Value = []byte(value)
+
+	if headers, ok := column("headers"); ok && strings.TrimSpace(headers) != "" {
+		var parsed map[string]string
+		if err := json.Unmarshal([]byte(headers), &parsed); err != nil {
+			return record, &kafka.RecordError{Line: r.line, Err: fmt.Errorf("headers must be a JSON object: %w", err)}
+		}
+		record.Message.Headers = headersFromMap(parsed)
+	}
+
+	if partition, ok := column("partition"); ok && strings.TrimSpace(partition) != "" {
+		n, err := strconv.ParseInt(strings.TrimSpace(partition), 10, 32)
+		if err != nil || n < 0 {
+			return record, &kafka.RecordError{Line: r.line, Err: fmt.Errorf("invalid partition %q", partition)}
+		}
+		record.Partition = int32(n)
+	}
+
+	return record, nil
+}
+
+func headersFromMap(headers map[string]string) []models.MessageHeader {
+	if len(headers) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]models.MessageHeader, 0, len(headers))
+	for _, key := range keys {
+		result = append(result, models.MessageHeader{Key: key, Value: []byte(headers[key])})
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package records
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/clemsau/kafe/internal/kafka"
+)
+
+// readAll reads every record of the source, collecting the lines of the
+// malformed ones
+func readAll(t *testing.T, source string, format Format) ([]kafka.BulkRecord, []int) {
+	t.Helper()
+	reader, err := NewReader(strings.NewReader(source), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []kafka.BulkRecord
+	var failed []int
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return records, failed
+		}
+		var recordErr *kafka.RecordError
+		if errors.As(err, &recordErr) {
+			failed = append(failed, recordErr.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestReadJSONLines(t *testing.T) {
+	source := `{"key": "order-1", "value": "plain text", "headers": {"b": "2", "a": "1"}}
+
+{"key": "order-2", "value": {"status": "PAID", "total": 12.5}, "partition": 3}
+{"value": 42}
+not json
+{"value": "x", "partition": -1}
+`
+	records, failed := readAll(t, source, FormatJSONL)
+
+	if len(records) != 3 {
+		t.Fatalf("read %d records, want 3", len(records))
+	}
+	if key, value := string(records[0].Message.Key), string(records[0].Message.Value); key != "order-1" || value != "plain text" {
+		t.Errorf("record 1 is %q=%q, want order-1=plain text", key, value)
+	}
+	if headers := records[0].Message.Headers; len(headers) != 2 || headers[0].Key != "a" || string(headers[1].Value) != "2" {
+		t.Errorf("record 1 headers are %v, want a=1 and b=2 in key order", headers)
+	}
+	if records[0].Partition != kafka.AnyPartition {
+		t.Errorf("record 1 partition is %d, want any", records[0].Partition)
+	}
+	if value := string(records[1].Message.Value); value != `{"status":"PAID","total":12.5}` {
+		t.Errorf("record 2 value is %s, want the compact document", value)
+	}
+	if records[1].Partition != 3 || records[1].Line != 3 {
+		t.Errorf("record 2 is partition %d on line %d, want partition 3 on line 3", records[1].Partition, records[1].Line)
+	}
+	if records[2].Message.Key != nil || string(records[2].Message.Value) != "42" {
+		t.Errorf("record 3 is %q=%q, want no key and 42", records[2].Message.Key, records[2].Message.Value)
+	}
+	if len(failed) != 2 || failed[0] != 5 || failed[1] != 6 {
+		t.Errorf("failed lines are %v, want [5 6]", failed)
+	}
+}
+
+func TestReadJSONNullValue(t *testing.T) {
+	records, _ := readAll(t, `{"key": "order-1", "value": null}`+"\n"+`{"key": "order-2", "value": ""}`, FormatJSONL)
+
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+	if records[0].Message.Value != nil {
+		t.Errorf("null value read as %q, want a tombstone", records[0].Message.Value)
+	}
+	if records[1].Message.Value == nil {
+		t.Error("empty string read as a tombstone, want an empty value")
+	}
+}
+
+func TestReadBase64(t *testing.T) {
+	source := `{"key_base64": "AAEC", "value_base64": "/w==", "key": "ignored"}
+{"value_base64": "not base64!"}
+`
+	records, failed := readAll(t, source, FormatJSONL)
+
+	if len(records) != 1 {
+		t.Fatalf("read %d records, want 1", len(records))
+	}
+	if key := records[0].Message.Key; string(key) != "\x00\x01\x02" {
+		t.Errorf("key is %q, want the decoded bytes", key)
+	}
+	if value := records[0].Message.Value; string(value) != "\xff" {
+		t.Errorf("value is %q, want the decoded bytes", value)
+	}
+	if len(failed) != 1 || failed[0] != 2 {
+		t.Errorf("failed lines are %v, want [2]", failed)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	source := `Key,Value,Headers,Partition
+order-1,"multi
+line",,
+,empty key,"{""trace"": ""abc""}",2
+order-3,bad headers,not json,
+order-4,bad partition,,x
+`
+	records, failed := readAll(t, source, FormatCSV)
+
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+	if key, value := string(records[0].Message.Key), string(records[0].Message.Value); key != "order-1" || value != "multi\nline" {
+		t.Errorf("record 1 is %q=%q, want order-1=multi\\nline", key, value)
+	}
+	if records[1].Message.Key != nil || records[1].Partition != 2 || records[1].Line != 4 {
+		t.Errorf("record 2 has key %q, partition %d and line %d, want no key, partition 2 and line 4",
+			records[1].Message.Key, records[1].Partition, records[1].Line)
+	}
+	if headers := records[1].Message.Headers; len(headers) != 1 || headers[0].Key != "trace" || string(headers[0].Value) != "abc" {
+		t.Errorf("record 2 headers are %v, want trace=abc", headers)
+	}
+	if len(failed) != 2 || failed[0] != 5 || failed[1] != 6 {
+		t.Errorf("failed lines are %v, want [5 6]", failed)
+	}
+}
+
+func TestReadCSVWithoutValueColumn(t *testing.T) {
+	if _, err := NewReader(strings.NewReader("key,payload\n"), FormatCSV); err == nil {
+		t.Error("got no error for a header without a value column")
+	}
+}
@@ -0,0 +1,236 @@
+package records
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/clemsau/kafe/internal/models"
+)
+
+// FormatRaw exports every key and value to its own binary file in a
+// directory, next to an index.jsonl holding the other fields
+const FormatRaw Format = "raw"
+
+// ExportFormats lists the formats accepted by NewFileWriter
+var ExportFormats = []Format{FormatJSONL, FormatCSV, FormatRaw}
+
+// Writer streams messages to a file
+type Writer interface {
+	Write(msg *models.Message) error
+	Close() error
+}
+
+// exportRecord is a message as written in JSON lines. Decoded keys and
+// values are written as their JSON text, and the ones that are not valid
+// UTF-8 are written to the base64 fields, so exports can be produced back
+// with the records reader.
+type exportRecord struct {
+	Topic       string            `json:"topic"`
+	Partition   int32             `json:"partition"`
+	Offset      int64             `json:"offset"`
+	Timestamp   string            `json:"timestamp"`
+	Key         *string           `json:"key,omitempty"`
+	KeyBase64   *string           `json:"key_base64,omitempty"`
+	Value       *string           `json:"value,omitempty"`
+	ValueBase64 *string           `json:"value_base64,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	KeyFile     string            `json:"key_file,omitempty"`
+	ValueFile   string            `json:"value_file,omitempty"`
+}
+
+func newExportRecord(msg *models.Message) exportRecord {
+	record := exportRecord{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp.Format(time.RFC3339Nano),
+		Headers:   headersToMap(msg.Headers),
+	}
+	record.Key, record.KeyBase64 = encodeBytes(msg.Key)
+	if msg.DecodedKey != nil {
+		record.Key, record.KeyBase64 = msg.DecodedKey, nil
+	}
+	record.Value, record.ValueBase64 = encodeBytes(msg.Value)
+	if msg.DecodedValue != nil {
+		record.Value, record.ValueBase64 = msg.DecodedValue, nil
+	}
+	return record
+}
+
+func encodeBytes(data []byte) (text *string, encoded *string) {
+	if data == nil {
+		return nil, nil
+	}
+	if utf8.Valid(data) {
+		s := string(data)
+		return &s, nil
+	}
+	s := base64.StdEncoding.EncodeToString(data)
+	return nil, &s
+}
+
+func headersToMap(headers []models.MessageHeader) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(headers))
+	for _, header := range headers {
+		result[header.Key] = string(header.Value)
+	}
+	return result
+}
+
+// NewFileWriter creates a writer for the format at path. For FormatRaw,
+// path is a directory that is created if needed. A path of "-" writes
+// JSON lines or CSV to the standard output.
+func NewFileWriter(path string, format Format) (Writer, error) {
+	if format == FormatRaw {
+		return newRawWriter(path)
+	}
+
+	var file io.WriteCloser = nopCloser{os.Stdout}
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+	}
+	return NewWriter(file, format)
+}
+
+// NewWriter creates a JSON lines or CSV writer closing w when closed
+func NewWriter(w io.WriteCloser, format Format) (Writer, error) {
+	buffered := bufio.NewWriter(w)
+	switch format {
+	case FormatJSONL:
+		encoder := json.NewEncoder(buffered)
+		encoder.SetEscapeHTML(false)
+		return &jsonWriter{file: w, buffer: buffered, encoder: encoder}, nil
+	case FormatCSV:
+		writer := &csvWriter{file: w, buffer: buffered, csv: csv.NewWriter(buffered)}
+		if err := writer.csv.Write([]string{"topic", "partition", "offset", "timestamp", "key", "value", "headers"}); err != nil {
+			return nil, err
+		}
+		return writer, nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+type jsonWriter struct {
+	file    io.Closer
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *jsonWriter) Write(msg *models.Message) error {
+	return w.encoder.Encode(newExportRecord(msg))
+}
+
+func (w *jsonWriter) Close() error {
+	if err := w.buffer.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+type csvWriter struct {
+	file   io.Closer
+	buffer *bufio.Writer
+	csv    *csv.Writer
+}
+
+// Write writes a CSV row. Keys and values are written as text, so binary
+// data is better exported as JSON lines or raw files.
+func (w *csvWriter) Write(msg *models.Message) error {
+	headers := ""
+	if len(msg.Headers) > 0 {
+		encoded, err := json.Marshal(headersToMap(msg.Headers))
+		if err != nil {
+			return err
+		}
+		headers = string(encoded)
+	}
+
+	return w.csv.Write([]string{
+		msg.Topic,
+		strconv.FormatInt(int64(msg.Partition), 10),
+		strconv.FormatInt(msg.Offset, 10),
+		msg.Timestamp.Format(time.RFC3339Nano),
+		msg.KeyText(),
+		msg.ValueText(),
+		headers,
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	if err := w.buffer.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// rawWriter writes <topic>-<partition>-<offset>.key and .value files and an
+// index
+type rawWriter struct {
+	dir   string
+	index *jsonWriter
+}
+
+func newRawWriter(dir string) (*rawWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	index, err := os.Create(filepath.Join(dir, "index.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	writer, _ := NewWriter(index, FormatJSONL)
+	return &rawWriter{dir: dir, index: writer.(*jsonWriter)}, nil
+}
+
+func (w *rawWriter) Write(msg *models.Message) error {
+	base := fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset)
+	record := newExportRecord(msg)
+	record.Key, record.KeyBase64, record.Value, record.ValueBase64 = nil, nil, nil, nil
+
+	if msg.Key != nil {
+		record.KeyFile = base + ".key"
+		if err := os.WriteFile(filepath.Join(w.dir, record.KeyFile), msg.Key, 0o644); err != nil {
+			return err
+		}
+	}
+	if msg.Value != nil {
+		record.ValueFile = base + ".value"
+		if err := os.WriteFile(filepath.Join(w.dir, record.ValueFile), msg.Value, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return w.index.encoder.Encode(record)
+}
+
+func (w *rawWriter) Close() error {
+	return w.index.Close()
+}
@@ -0,0 +1,178 @@
+// Package rules evaluates the status rules of a context against the topics
+// and consumer groups, and gives the color of each status.
+package rules
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/gdamore/tcell/v2"
+)
+
+// defaultGrowthWindow is the window a lag growth is measured over
+const defaultGrowthWindow = 5 * time.Minute
+
+// Defaults are the rules of a context without rules
+var Defaults = []config.Rule{
+	{Group: "*", MaxTimeLag: "5m", Status: "Lagging"},
+	{Topic: "*", UnderReplicated: true, Status: "Warning"},
+}
+
+// defaultColors are the colors of the built-in statuses
+var defaultColors = map[string]tcell.Color{
+	"Ready":              tcell.ColorGreen,
+	"Active":             tcell.ColorGreen,
+	"Warning":            tcell.ColorYellow,
+	"Lagging":            tcell.ColorYellow,
+	"Error":              tcell.ColorRed,
+	"Dead":               tcell.ColorRed,
+	models.StatusDeleted: tcell.ColorGray,
+}
+
+// GroupMetrics are the measures of a group the rules compare, beyond its
+// info
+type GroupMetrics struct {
+	// LagGrowth returns the per second growth of the lag over a window,
+	// false when the history is too short
+	LagGrowth func(window time.Duration) (float64, bool)
+}
+
+type rule struct {
+	config.Rule
+	maxTimeLag   time.Duration
+	growthWindow time.Duration
+}
+
+// Rules are the parsed status rules of a context
+type Rules struct {
+	rules  []rule
+	colors map[string]tcell.Color
+}
+
+// New parses rules, the default rules when there is none
+func New(rules []config.Rule) (*Rules, error) {
+	if len(rules) == 0 {
+		rules = Defaults
+	}
+
+	r := &Rules{colors: make(map[string]tcell.Color)}
+	for name, color := range defaultColors {
+		r.colors[name] = color
+	}
+
+	for i, cfg := range rules {
+		if cfg.Status == "" {
+			return nil, fmt.Errorf("rule %d: status is required", i+1)
+		}
+		groupThreshold := cfg.MaxLag > 0 || cfg.MaxTimeLag != "" || cfg.MaxLagGrowth > 0
+		topicThreshold := cfg.UnderReplicated || cfg.MinInSync > 0
+		switch {
+		case !groupThreshold && !topicThreshold:
+			return nil, fmt.Errorf("rule %d: no threshold set", i+1)
+		case groupThreshold && cfg.Group == "":
+			return nil, fmt.Errorf("rule %d: max-lag, max-time-lag and max-lag-growth need a group pattern", i+1)
+		case topicThreshold && cfg.Group != "":
+			return nil, fmt.Errorf("rule %d: under-replicated and min-in-sync apply to topics, not groups", i+1)
+		}
+		for _, pattern := range []string{cfg.Topic, cfg.Group} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid pattern %q", i+1, pattern)
+			}
+		}
+
+		parsed := rule{Rule: cfg, growthWindow: defaultGrowthWindow}
+		if cfg.MaxTimeLag != "" {
+			d, err := time.ParseDuration(cfg.MaxTimeLag)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: max-time-lag: %w", i+1, err)
+			}
+			parsed.maxTimeLag = d
+		}
+		if cfg.GrowthWindow != "" {
+			d, err := time.ParseDuration(cfg.GrowthWindow)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("rule %d: invalid growth-window %q", i+1, cfg.GrowthWindow)
+			}
+			parsed.growthWindow = d
+		}
+		if cfg.Color != "" {
+			color := tcell.GetColor(cfg.Color)
+			if color == tcell.ColorDefault {
+				return nil, fmt.Errorf("rule %d: unknown color %q", i+1, cfg.Color)
+			}
+			r.colors[cfg.Status] = color
+		}
+		r.rules = append(r.rules, parsed)
+	}
+	return r, nil
+}
+
+// TopicStatus returns the status of a topic: its health when it is not
+// Ready, otherwise the status of the first topic rule it exceeds
+func (r *Rules) TopicStatus(info models.TopicInfo) string {
+	if info.Status != "Ready" {
+		return info.Status
+	}
+
+	for _, rule := range r.rules {
+		if rule.Group != "" || !matches(rule.Topic, info.Name) {
+			continue
+		}
+		if rule.UnderReplicated && info.UnderReplicated > 0 ||
+			rule.MinInSync > 0 && info.MinInSync >= 0 && info.MinInSync < rule.MinInSync {
+			return rule.Status
+		}
+	}
+	return info.Status
+}
+
+// GroupStatus returns the status of a consumer group on a topic: Dead when
+// it is, otherwise the status of the first group rule it exceeds
+func (r *Rules) GroupStatus(group models.ConsumerGroupInfo, metrics GroupMetrics) string {
+	if group.Status != "Active" {
+		return group.Status
+	}
+
+	for _, rule := range r.rules {
+		if rule.Group == "" || !matches(rule.Group, group.ID) || !matches(rule.Topic, group.Topic) {
+			continue
+		}
+		if rule.MaxLag > 0 && group.TotalLag > rule.MaxLag ||
+			rule.maxTimeLag > 0 && group.TimeLag > rule.maxTimeLag {
+			return rule.Status
+		}
+		if rule.MaxLagGrowth > 0 && metrics.LagGrowth != nil {
+			if growth, ok := metrics.LagGrowth(rule.growthWindow); ok && growth > rule.MaxLagGrowth {
+				return rule.Status
+			}
+		}
+	}
+	return group.Status
+}
+
+// Healthy tells whether a status is the status of a healthy topic or of an
+// active group no rule flagged
+func Healthy(status string) bool {
+	return status == "Ready" || status == "Active"
+}
+
+// Color returns the color of a status, white for unknown statuses
+func (r *Rules) Color(status string) tcell.Color {
+	if color, ok := r.colors[status]; ok {
+		return color
+	}
+	return tcell.ColorWhite
+}
+
+// matches tells whether name matches a glob pattern, an empty pattern
+// matching every name
+func matches(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
@@ -0,0 +1,87 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestGroupStatus(t *testing.T) {
+	r, err := New([]config.Rule{
+		{Group: "billing-*", MaxTimeLag: "1m", Status: "Critical", Color: "red"},
+		{Group: "*", MaxLagGrowth: 10, Status: "Growing"},
+		{Group: "*", Topic: "orders", MaxLag: 1000, Status: "Lagging"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	growth := func(rate float64) GroupMetrics {
+		return GroupMetrics{LagGrowth: func(time.Duration) (float64, bool) { return rate, true }}
+	}
+	tests := []struct {
+		group   models.ConsumerGroupInfo
+		metrics GroupMetrics
+		want    string
+	}{
+		{models.ConsumerGroupInfo{ID: "billing-1", Topic: "orders", Status: "Active", TimeLag: 2 * time.Minute}, GroupMetrics{}, "Critical"},
+		{models.ConsumerGroupInfo{ID: "audit", Topic: "orders", Status: "Active", TimeLag: 2 * time.Minute}, GroupMetrics{}, "Active"},
+		{models.ConsumerGroupInfo{ID: "audit", Topic: "orders", Status: "Active", TotalLag: 5000}, GroupMetrics{}, "Lagging"},
+		{models.ConsumerGroupInfo{ID: "audit", Topic: "payments", Status: "Active", TotalLag: 5000}, GroupMetrics{}, "Active"},
+		{models.ConsumerGroupInfo{ID: "audit", Topic: "payments", Status: "Active"}, growth(50), "Growing"},
+		{models.ConsumerGroupInfo{ID: "billing-1", Topic: "orders", Status: "Dead", TimeLag: time.Hour}, GroupMetrics{}, "Dead"},
+	}
+	for _, test := range tests {
+		if got := r.GroupStatus(test.group, test.metrics); got != test.want {
+			t.Errorf("status of %s on %s is %q, want %q", test.group.ID, test.group.Topic, got, test.want)
+		}
+	}
+
+	if color := r.Color("Critical"); color != tcell.GetColor("red") {
+		t.Errorf("color of Critical is %v, want red", color)
+	}
+}
+
+func TestTopicStatus(t *testing.T) {
+	r, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.TopicStatus(models.TopicInfo{Name: "orders", Status: "Ready", UnderReplicated: 1}); got != "Warning" {
+		t.Errorf("under-replicated topic is %q, want Warning", got)
+	}
+	if got := r.TopicStatus(models.TopicInfo{Name: "orders", Status: "Error", UnderReplicated: 1}); got != "Error" {
+		t.Errorf("topic without leader is %q, want Error", got)
+	}
+	if got := r.TopicStatus(models.TopicInfo{Name: "orders", Status: "Ready"}); got != "Ready" {
+		t.Errorf("healthy topic is %q, want Ready", got)
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	for _, rule := range []config.Rule{
+		{Group: "*"},
+		{Group: "*", Status: "Lagging"},
+		{Topic: "orders", MaxLag: 1000, Status: "Lagging"},
+		{MaxTimeLag: "5m", Status: "Lagging"},
+		{Group: "*", UnderReplicated: true, Status: "Warning"},
+		{Group: "[", Status: "Lagging"},
+		{Group: "*", MaxTimeLag: "soon", Status: "Lagging"},
+		{Group: "*", GrowthWindow: "-1m", Status: "Lagging"},
+		{Topic: "*", Status: "Warning", Color: "not-a-color"},
+	} {
+		if _, err := New([]config.Rule{rule}); err == nil {
+			t.Errorf("rule %+v was accepted", rule)
+		}
+	}
+
+	_, err := New([]config.Rule{{Group: "*", MaxLag: 10, Status: "Lagging"}, {MaxLag: 10, Status: "Lagging"}})
+	if err == nil || !strings.HasPrefix(err.Error(), "rule 2:") {
+		t.Errorf("error %v does not name rule 2", err)
+	}
+}
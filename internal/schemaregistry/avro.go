@@ -0,0 +1,38 @@
+package schemaregistry
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// avroDecoder decodes Avro binary payloads to Avro JSON
+type avroDecoder struct {
+	codec *goavro.Codec
+}
+
+func newAvroDecoder(schema *Schema) (*avroDecoder, error) {
+	if len(schema.References) > 0 {
+		return nil, errors.New("avro schema references are not supported")
+	}
+
+	codec, err := goavro.NewCodec(schema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %w", err)
+	}
+	return &avroDecoder{codec: codec}, nil
+}
+
+func (d *avroDecoder) decode(payload []byte) (string, error) {
+	native, _, err := d.codec.NativeFromBinary(payload)
+	if err != nil {
+		return "", fmt.Errorf("avro decode: %w", err)
+	}
+
+	text, err := d.codec.TextualFromNative(nil, native)
+	if err != nil {
+		return "", fmt.Errorf("avro to json: %w", err)
+	}
+	return string(text), nil
+}
@@ -0,0 +1,286 @@
+// Package schemaregistry fetches schemas from a Confluent compatible schema
+// registry and decodes the keys and values framed in its wire format.
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/config"
+)
+
+// magicByte starts every payload in the schema registry wire format,
+// followed by the 4-byte big-endian schema ID
+const magicByte = 0
+
+// requestTimeout bounds a single registry request
+const requestTimeout = 10 * time.Second
+
+// retryInterval is how long a schema is not fetched again after a failed
+// request, so an unreachable registry does not stall every decode
+const retryInterval = 30 * time.Second
+
+// ErrNotFramed is returned for data that is not in the wire format
+var ErrNotFramed = errors.New("not in the schema registry wire format")
+
+// Schema types as reported by the registry
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+	TypeJSON     = "JSON"
+)
+
+// Reference points to a schema imported by another one
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema is a registered schema
+type Schema struct {
+	ID         int
+	Type       string
+	Schema     string
+	References []Reference
+
+	// decoder is compiled on first use and shared by later decodes
+	decoder decoder
+	once    sync.Once
+	err     error
+}
+
+// decoder turns a framed payload, without the magic byte and schema ID,
+// into JSON text
+type decoder interface {
+	decode(payload []byte) (string, error)
+}
+
+// Client fetches schemas from a registry and caches them by ID
+type Client struct {
+	url      string
+	http     *http.Client
+	username string
+	password string
+	mutex    sync.Mutex
+	schemas  map[int]*Schema
+	missing  map[int]error
+	failed   map[int]failure
+}
+
+// failure is a failed schema request, retried after retryAt
+type failure struct {
+	err     error
+	retryAt time.Time
+}
+
+// NewClient creates a client for the registry described by cfg
+func NewClient(cfg config.SchemaRegistryConfig) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("schema registry URL is empty")
+	}
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("invalid schema registry URL: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !cfg.TLS.IsZero() {
+		tlsConfig, err := cfg.TLS.Build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &Client{
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		http:     &http.Client{Transport: transport, Timeout: requestTimeout},
+		username: cfg.Username,
+		password: cfg.Password,
+		schemas:  make(map[int]*Schema),
+		missing:  make(map[int]error),
+		failed:   make(map[int]failure),
+	}, nil
+}
+
+// ParseWireFormat splits framed data into its schema ID and payload
+func ParseWireFormat(data []byte) (int, []byte, error) {
+	if len(data) < 5 || data[0] != magicByte {
+		return 0, nil, ErrNotFramed
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
+
+// Decode decodes framed data to JSON text, fetching its schema if needed.
+// It returns ErrNotFramed when data is not in the wire format.
+func (c *Client) Decode(data []byte) (string, error) {
+	id, payload, err := ParseWireFormat(data)
+	if err != nil {
+		return "", err
+	}
+
+	schema, err := c.Schema(id)
+	if err != nil {
+		return "", err
+	}
+
+	schema.once.Do(func() {
+		schema.decoder, schema.err = c.compile(schema)
+	})
+	if schema.err != nil {
+		return "", fmt.Errorf("schema %d: %w", id, schema.err)
+	}
+
+	text, err := schema.decoder.decode(payload)
+	if err != nil {
+		return "", fmt.Errorf("schema %d: %w", id, err)
+	}
+	return text, nil
+}
+
+// compile builds the decoder matching the schema type
+func (c *Client) compile(schema *Schema) (decoder, error) {
+	switch schema.Type {
+	case TypeAvro:
+		return newAvroDecoder(schema)
+	case TypeProtobuf:
+		return c.newProtobufDecoder(schema)
+	case TypeJSON:
+		// payloads are validated against the schema by producers, they only
+		// need to be extracted from the frame
+		return jsonDecoder{}, nil
+	}
+	return nil, fmt.Errorf("%s schemas are not supported", schema.Type)
+}
+
+type schemaResponse struct {
+	Schema     string      `json:"schema"`
+	SchemaType string      `json:"schemaType"`
+	References []Reference `json:"references"`
+}
+
+// Schema returns the schema registered under id, from the cache when
+// possible. Unknown IDs are not fetched again, and IDs whose request failed
+// not before retryInterval.
+func (c *Client) Schema(id int) (*Schema, error) {
+	c.mutex.Lock()
+	if schema, ok := c.schemas[id]; ok {
+		c.mutex.Unlock()
+		return schema, nil
+	}
+	if err, ok := c.missing[id]; ok {
+		c.mutex.Unlock()
+		return nil, err
+	}
+	if f, ok := c.failed[id]; ok && time.Now().Before(f.retryAt) {
+		c.mutex.Unlock()
+		return nil, f.err
+	}
+	c.mutex.Unlock()
+
+	var resp schemaResponse
+	status, err := c.get(fmt.Sprintf("/schemas/ids/%d", id), &resp)
+	if err != nil {
+		c.mutex.Lock()
+		if status == http.StatusNotFound {
+			c.missing[id] = err
+		} else {
+			c.failed[id] = failure{err: err, retryAt: time.Now().Add(retryInterval)}
+		}
+		c.mutex.Unlock()
+		return nil, err
+	}
+
+	schema := &Schema{
+		ID:         id,
+		Type:       resp.SchemaType,
+		Schema:     resp.Schema,
+		References: resp.References,
+	}
+	if schema.Type == "" { // the registry omits the type of Avro schemas
+		schema.Type = TypeAvro
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.failed, id)
+	if cached, ok := c.schemas[id]; ok {
+		return cached, nil
+	}
+	c.schemas[id] = schema
+	return schema, nil
+}
+
+type subjectVersionResponse struct {
+	ID         int         `json:"id"`
+	Schema     string      `json:"schema"`
+	SchemaType string      `json:"schemaType"`
+	References []Reference `json:"references"`
+}
+
+// SubjectVersion returns a schema by subject and version, as used by references
+func (c *Client) SubjectVersion(subject string, version int) (*Schema, error) {
+	var resp subjectVersionResponse
+	path := fmt.Sprintf("/subjects/%s/versions/%d", url.PathEscape(subject), version)
+	if _, err := c.get(path, &resp); err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{
+		ID:         resp.ID,
+		Type:       resp.SchemaType,
+		Schema:     resp.Schema,
+		References: resp.References,
+	}
+	if schema.Type == "" {
+		schema.Type = TypeAvro
+	}
+	return schema, nil
+}
+
+// get performs a registry request and decodes its JSON response into v
+func (c *Client) get(path string, v any) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("schema registry request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read schema registry response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var registryErr struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		if json.Unmarshal(body, &registryErr) == nil && registryErr.Message != "" {
+			return resp.StatusCode, fmt.Errorf("schema registry: %s (%d)", registryErr.Message, registryErr.ErrorCode)
+		}
+		return resp.StatusCode, fmt.Errorf("schema registry: unexpected status %s", resp.Status)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, fmt.Errorf("invalid schema registry response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
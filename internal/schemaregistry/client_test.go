@@ -0,0 +1,159 @@
+package schemaregistry
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/linkedin/goavro/v2"
+)
+
+const orderSchema = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"qty","type":"int"}]}`
+
+const (
+	shopSchema = `syntax = "proto3";
+package shop;
+import "common.proto";
+
+message Order {
+  message Line {
+    string sku = 1;
+    int32 qty = 2;
+  }
+  string id = 1;
+  repeated Line lines = 2;
+  common.Money total = 3;
+}
+`
+	commonSchema = `syntax = "proto3";
+package common;
+
+message Money {
+  int64 cents = 1;
+  string currency = 2;
+}
+`
+)
+
+// registry serves the order schema under ID 1, 404s on ID 2, serves a
+// protobuf schema referencing the common subject under ID 4 and fails on
+// any other ID, counting the requests by path
+type registry struct {
+	mutex    sync.Mutex
+	requests map[string]int
+	auth     []string
+}
+
+func (r *registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mutex.Lock()
+	r.requests[req.URL.Path]++
+	if username, password, ok := req.BasicAuth(); ok {
+		r.auth = append(r.auth, username+":"+password)
+	}
+	r.mutex.Unlock()
+
+	switch req.URL.Path {
+	case "/schemas/ids/1":
+		json.NewEncoder(w).Encode(map[string]string{"schema": orderSchema})
+	case "/schemas/ids/4":
+		json.NewEncoder(w).Encode(map[string]any{
+			"schemaType": TypeProtobuf,
+			"schema":     shopSchema,
+			"references": []Reference{{Name: "common.proto", Subject: "common", Version: 1}},
+		})
+	case "/subjects/common/versions/1":
+		json.NewEncoder(w).Encode(map[string]any{"id": 5, "schemaType": TypeProtobuf, "schema": commonSchema})
+	case "/schemas/ids/2":
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (r *registry) count(path string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.requests[path]
+}
+
+func newTestClient(t *testing.T) (*Client, *registry) {
+	reg := &registry{requests: make(map[string]int)}
+	server := httptest.NewServer(reg)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(config.SchemaRegistryConfig{URL: server.URL + "/", Username: "kafe", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, reg
+}
+
+// frame prefixes the payload with the magic byte and the schema ID
+func frame(id int, payload []byte) []byte {
+	data := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(data[1:], uint32(id))
+	return append(data, payload...)
+}
+
+func TestDecodeAvroCachesSchema(t *testing.T) {
+	client, reg := newTestClient(t)
+
+	codec, err := goavro.NewCodec(orderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, qty := range []int32{3, 4} {
+		payload, err := codec.BinaryFromNative(nil, map[string]any{"id": "order-42", "qty": qty})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := client.Decode(frame(1, payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf(`{"id":"order-42","qty":%d}`, qty); text != want {
+			t.Errorf("decoded %s, want %s", text, want)
+		}
+	}
+
+	if n := reg.count("/schemas/ids/1"); n != 1 {
+		t.Errorf("schema fetched %d times, want once", n)
+	}
+	if len(reg.auth) != 1 || reg.auth[0] != "kafe:secret" {
+		t.Errorf("basic auth is %v, want kafe:secret", reg.auth)
+	}
+}
+
+func TestSchemaCachesFailures(t *testing.T) {
+	client, reg := newTestClient(t)
+
+	for _, id := range []int{2, 3} {
+		for i := 0; i < 2; i++ {
+			if _, err := client.Schema(id); err == nil {
+				t.Errorf("schema %d: got no error", id)
+			}
+		}
+	}
+	if n := reg.count("/schemas/ids/2"); n != 1 {
+		t.Errorf("missing schema fetched %d times, want once", n)
+	}
+	if n := reg.count("/schemas/ids/3"); n != 1 {
+		t.Errorf("failed schema fetched %d times within the retry interval, want once", n)
+	}
+}
+
+func TestDecodeNotFramed(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	for _, data := range [][]byte{nil, []byte("plain"), {0, 0, 0}} {
+		if _, err := client.Decode(data); err != ErrNotFramed {
+			t.Errorf("Decode(%q) error = %v, want ErrNotFramed", data, err)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonDecoder decodes JSON Schema payloads, which are plain JSON documents
+type jsonDecoder struct{}
+
+func (jsonDecoder) decode(payload []byte) (string, error) {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, payload); err != nil {
+		return "", fmt.Errorf("invalid json payload: %w", err)
+	}
+	return compacted.String(), nil
+}
@@ -0,0 +1,69 @@
+package schemaregistry
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/clemsau/kafe/internal/protobuf"
+)
+
+// protobufDecoder decodes protobuf payloads prefixed with the indexes of
+// their message in the schema
+type protobufDecoder struct {
+	file protoreflect.FileDescriptor
+}
+
+func (c *Client) newProtobufDecoder(schema *Schema) (*protobufDecoder, error) {
+	// the references are imported by name, the schema itself needs a name
+	// that cannot clash with them
+	name := fmt.Sprintf("kafe-schema-%d.proto", schema.ID)
+	sources := map[string]string{name: schema.Schema}
+	if err := c.resolveReferences(schema.References, sources); err != nil {
+		return nil, err
+	}
+
+	files, err := protobuf.CompileSources(sources, name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf schema: %w", err)
+	}
+
+	file, err := files.File(name)
+	if err != nil {
+		return nil, err
+	}
+	return &protobufDecoder{file: file}, nil
+}
+
+// resolveReferences fetches the referenced schemas, and their own
+// references, into sources
+func (c *Client) resolveReferences(references []Reference, sources map[string]string) error {
+	for _, ref := range references {
+		if _, ok := sources[ref.Name]; ok {
+			continue
+		}
+
+		schema, err := c.SubjectVersion(ref.Subject, ref.Version)
+		if err != nil {
+			return fmt.Errorf("reference %s: %w", ref.Name, err)
+		}
+		sources[ref.Name] = schema.Schema
+		if err := c.resolveReferences(schema.References, sources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *protobufDecoder) decode(payload []byte) (string, error) {
+	indexes, data, err := protobuf.ParseMessageIndexes(payload)
+	if err != nil {
+		return "", err
+	}
+
+	md, err := protobuf.MessageAt(d.file, indexes)
+	if err != nil {
+		return "", err
+	}
+	return protobuf.Decode(md, data)
+}
@@ -0,0 +1,56 @@
+package schemaregistry
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/clemsau/kafe/internal/protobuf"
+)
+
+func TestDecodeProtobuf(t *testing.T) {
+	client, reg := newTestClient(t)
+
+	files, err := protobuf.CompileSources(map[string]string{"shop.proto": shopSchema, "common.proto": commonSchema}, "shop.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		message string
+		indexes []byte
+		text    string
+	}{
+		{"shop.Order", []byte{0}, `{"id":"order-42","total":{"cents":"1250","currency":"EUR"}}`},
+		{"shop.Order", []byte{2, 0}, `{"id":"order-43"}`},
+		{"shop.Order.Line", []byte{4, 0, 0}, `{"sku":"A-1","qty":3}`},
+	}
+	for _, test := range tests {
+		md, err := files.Message(test.message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := dynamicpb.NewMessage(md)
+		if err := protojson.Unmarshal([]byte(test.text), msg); err != nil {
+			t.Fatal(err)
+		}
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		text, err := client.Decode(frame(4, append(test.indexes, data...)))
+		if err != nil || text != test.text {
+			t.Errorf("Decode(%s at %v) = %s, %v, want %s", test.message, test.indexes, text, err, test.text)
+		}
+	}
+
+	if _, err := client.Decode(frame(4, []byte{2, 2})); err == nil {
+		t.Error("Decode with a missing message index: got no error")
+	}
+	if n := reg.count("/subjects/common/versions/1"); n != 1 {
+		t.Errorf("reference fetched %d times, want once", n)
+	}
+}
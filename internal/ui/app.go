@@ -1,6 +1,9 @@
 package ui
 
 import (
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/clemsau/kafe/internal/rules"
+	"github.com/clemsau/kafe/internal/watch"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -9,13 +12,34 @@ import (
 type App struct {
 	*tview.Application
 	pages *tview.Pages
+	// contexts are the cluster contexts of the configuration file, and
+	// context the one the application is connected to
+	contexts []config.Context
+	context  string
+	// rules set the statuses of the topics and consumer groups
+	rules *rules.Rules
+	// watchList holds the topics and groups pinned on the context
+	watchList *watch.List
+}
+
+// WatchedColor is the color of the names of the watched topics and groups
+const WatchedColor = tcell.ColorAqua
+
+// textInput is implemented by the widgets accepting free text
+type textInput interface {
+	GetLabel() string
+	GetText() string
 }
 
 // NewApp creates a new UI application
 func NewApp() *App {
+	defaults, _ := rules.New(nil)
+	watchList, _ := watch.Load("", "")
 	app := &App{
 		Application: tview.NewApplication(),
 		pages:       tview.NewPages(),
+		rules:       defaults,
+		watchList:   watchList,
 	}
 
 	app.SetRoot(app.pages, true)
@@ -32,6 +56,44 @@ func (a *App) RemovePage(name string) {
 	a.pages.RemovePage(name)
 }
 
+// SetContexts sets the cluster contexts of the configuration file and the
+// name of the one the application is connected to
+func (a *App) SetContexts(contexts []config.Context, current string) {
+	a.contexts = contexts
+	a.context = current
+}
+
+// Contexts returns the cluster contexts of the configuration file
+func (a *App) Contexts() []config.Context {
+	return a.contexts
+}
+
+// CurrentContext returns the name of the context the application is
+// connected to
+func (a *App) CurrentContext() string {
+	return a.context
+}
+
+// SetRules sets the status rules of the context
+func (a *App) SetRules(r *rules.Rules) {
+	a.rules = r
+}
+
+// Rules returns the status rules of the context
+func (a *App) Rules() *rules.Rules {
+	return a.rules
+}
+
+// SetWatchList sets the watch list of the context
+func (a *App) SetWatchList(list *watch.List) {
+	a.watchList = list
+}
+
+// WatchList returns the watch list of the context
+func (a *App) WatchList() *watch.List {
+	return a.watchList
+}
+
 // SetGlobalInputHandler sets up global keyboard shortcuts
 func (a *App) SetGlobalInputHandler(handler func(*tcell.EventKey) *tcell.EventKey) {
 	a.Application.SetInputCapture(handler)
@@ -39,6 +101,11 @@ func (a *App) SetGlobalInputHandler(handler func(*tcell.EventKey) *tcell.EventKe
 
 // DefaultGlobalHandler provides common keyboard shortcuts
 func (a *App) DefaultGlobalHandler(event *tcell.EventKey) *tcell.EventKey {
+	// Let text inputs receive every rune, e.g. a 'q' typed in a filter
+	if _, ok := a.GetFocus().(textInput); ok {
+		return event
+	}
+
 	switch event.Key() {
 	case tcell.KeyRune:
 		switch event.Rune() {
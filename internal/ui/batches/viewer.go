@@ -0,0 +1,286 @@
+// Package batches shows the record batches of a partition as stored by
+// the broker, for debugging producers.
+package batches
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/clemsau/kafe/internal/ui/utils"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// defaultFetchBytes is how many bytes of batches a page fetches by default
+const defaultFetchBytes = 1024 * 1024
+
+// BatchViewer lists the record batches of a topic partition with their
+// metadata and records
+type BatchViewer struct {
+	*tview.Flex
+	form    *tview.Form
+	batches *tview.TextView
+	topBar  *topbar.TopBar
+	app     *ui.App
+	client  *kafka.Client
+	topic   string
+
+	partition int32
+	maxBytes  int32
+	next      int64
+	loaded    bool
+	// fetching is set while a page is fetched in the background
+	fetching bool
+}
+
+// NewBatchViewer creates a batch inspection page for the topic
+func NewBatchViewer(app *ui.App, client *kafka.Client, topic string) *BatchViewer {
+	bv := &BatchViewer{
+		form: tview.NewForm(),
+		batches: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true).
+			SetWrap(true),
+		app:    app,
+		client: client,
+		topic:  topic,
+	}
+
+	bv.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Esc", Description: "back to topics"},
+		{Key: "e", Description: "edit position"},
+		{Key: "n", Description: "next batches"},
+		{Key: "q", Description: "quit"},
+	})
+
+	bv.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(bv.topBar, bv.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(bv.form, 11, 0, true).
+		AddItem(bv.batches, 0, 1, false)
+
+	bv.setupUI()
+	return bv
+}
+
+func (bv *BatchViewer) setupUI() {
+	bv.form.
+		AddInputField("Partition", "0", 10, tview.InputFieldInteger, nil).
+		AddInputField("From", "earliest", 40, nil, nil).
+		AddInputField("Max bytes", strconv.Itoa(defaultFetchBytes), 20, tview.InputFieldInteger, nil).
+		AddButton("Fetch", bv.start).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle(fmt.Sprintf(" Record batches - %s (times, earliest, latest or @offset) ", bv.topic)).
+		SetTitleAlign(tview.AlignLeft)
+
+	bv.batches.SetBorder(true).
+		SetTitle(" Batches ").
+		SetTitleAlign(tview.AlignLeft)
+
+	bv.SetInputCapture(bv.handleInput)
+}
+
+func (bv *BatchViewer) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	if bv.form.HasFocus() {
+		if event.Key() == tcell.KeyEscape {
+			bv.app.SetFocus(bv.batches)
+			return nil
+		}
+		return event
+	}
+
+	switch event.Key() {
+	case tcell.KeyEscape:
+		bv.app.RemovePage("batches")
+		return nil
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'e':
+			bv.app.SetFocus(bv.form)
+			return nil
+		case 'n':
+			if bv.loaded {
+				bv.fetchNext()
+			}
+			return nil
+		}
+	}
+	return event
+}
+
+// start parses the form and fetches the first page of batches
+func (bv *BatchViewer) start() {
+	partition, err := strconv.ParseInt(bv.form.GetFormItemByLabel("Partition").(*tview.InputField).GetText(), 10, 32)
+	if err != nil {
+		bv.showError(fmt.Errorf("partition: %w", err))
+		return
+	}
+	pos, err := kafka.ParsePosition(bv.form.GetFormItemByLabel("From").(*tview.InputField).GetText(), time.Now())
+	if err != nil {
+		bv.showError(fmt.Errorf("from: %w", err))
+		return
+	}
+	maxBytes, err := strconv.ParseInt(bv.form.GetFormItemByLabel("Max bytes").(*tview.InputField).GetText(), 10, 32)
+	if err != nil || maxBytes <= 0 {
+		bv.showError(fmt.Errorf("max bytes must be a positive number"))
+		return
+	}
+
+	if bv.fetching {
+		return
+	}
+	bv.fetching = true
+	bv.app.SetFocus(bv.batches)
+
+	go func() {
+		offset, err := bv.client.ResolveOffset(bv.topic, int32(partition), pos)
+		var page *kafka.BatchPage
+		var text string
+		if err == nil {
+			page, text, err = bv.fetch(int32(partition), offset, int32(maxBytes))
+		}
+
+		bv.app.QueueUpdateDraw(func() {
+			bv.fetching = false
+			if err != nil {
+				bv.showError(err)
+				return
+			}
+			bv.partition = int32(partition)
+			bv.maxBytes = int32(maxBytes)
+			bv.next = offset
+			bv.loaded = true
+			bv.batches.Clear()
+			bv.show(page, text)
+		})
+	}()
+}
+
+// fetchNext appends the batches following the displayed ones
+func (bv *BatchViewer) fetchNext() {
+	if bv.fetching {
+		return
+	}
+	bv.fetching = true
+	partition, offset, maxBytes := bv.partition, bv.next, bv.maxBytes
+
+	go func() {
+		page, text, err := bv.fetch(partition, offset, maxBytes)
+		bv.app.QueueUpdateDraw(func() {
+			bv.fetching = false
+			if err != nil {
+				bv.showError(err)
+				return
+			}
+			bv.show(page, text)
+		})
+	}()
+}
+
+// fetch fetches a page of batches and writes them out, off the UI
+// goroutine as decoding the records may query the schema registry
+func (bv *BatchViewer) fetch(partition int32, offset int64, maxBytes int32) (*kafka.BatchPage, string, error) {
+	page, err := bv.client.FetchBatches(bv.topic, partition, offset, maxBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var text strings.Builder
+	for i := range page.Batches {
+		writeBatch(&text, bv.client, &page.Batches[i])
+	}
+	return page, text.String(), nil
+}
+
+// show appends a fetched page to the displayed batches
+func (bv *BatchViewer) show(page *kafka.BatchPage, text string) {
+	bv.batches.SetTitle(fmt.Sprintf(" Batches - partition %d - log start %d, last stable %d, high watermark %d ",
+		bv.partition, page.LogStartOffset, page.LastStableOffset, page.HighWaterMark))
+
+	if len(page.Batches) == 0 {
+		fmt.Fprintf(bv.batches, "[yellow]No batches from offset %d[-]\n", bv.next)
+		return
+	}
+	fmt.Fprint(bv.batches, text)
+	bv.next = page.Next
+}
+
+// writeBatch writes the metadata of a batch followed by its records.
+// Control records and aborted records are coloured instead of hidden.
+func writeBatch(w *strings.Builder, client *kafka.Client, batch *kafka.RecordBatch) {
+	flags := []string{fmt.Sprintf("v%d", batch.Version)}
+	if batch.Transactional {
+		flags = append(flags, "transactional")
+	}
+	if batch.Control {
+		flags = append(flags, "control "+batch.ControlType)
+	}
+	if batch.LogAppendTime {
+		flags = append(flags, "log append time")
+	}
+	if batch.Aborted {
+		flags = append(flags, "ABORTED")
+	}
+
+	color := "yellow"
+	switch {
+	case batch.Aborted:
+		color = "red"
+	case batch.Control:
+		color = "fuchsia"
+	}
+
+	fmt.Fprintf(w, "[%s::b]Batch %d-%d[-::-] [%s]%d records, %s, %s uncompressed, producer %d epoch %d, base sequence %d, %s[-]\n",
+		color,
+		batch.FirstOffset,
+		batch.LastOffset,
+		color,
+		len(batch.Records),
+		batch.Codec,
+		utils.FormatBytes(batch.Size),
+		batch.ProducerID,
+		batch.ProducerEpoch,
+		batch.BaseSequence,
+		strings.Join(flags, ", "))
+	fmt.Fprintf(w, "  [gray]timestamps %s to %s[-]\n",
+		batch.FirstTimestamp.Format("2006-01-02 15:04:05.000"),
+		batch.MaxTimestamp.Format("2006-01-02 15:04:05.000"))
+
+	for i := range batch.Records {
+		msg := &batch.Records[i]
+		if batch.Control {
+			fmt.Fprintf(w, "  [fuchsia]@%d %s marker[-]\n", msg.Offset, batch.ControlType)
+			continue
+		}
+
+		client.DecodeMessage(msg)
+		prefix, color := "", "green"
+		if batch.Aborted {
+			prefix, color = "ABORTED ", "red"
+		}
+		fmt.Fprintf(w, "  [%s]%s@%d %s[-] %s %s\n",
+			color,
+			prefix,
+			msg.Offset,
+			msg.Timestamp.Format("15:04:05.000"),
+			tview.Escape(msg.KeyText()),
+			tview.Escape(msg.ValueText()))
+		if msg.DecodeErr != nil {
+			fmt.Fprintf(w, "  [red]  decode error: %s[-]\n", tview.Escape(msg.DecodeErr.Error()))
+		}
+	}
+}
+
+func (bv *BatchViewer) showError(err error) {
+	bv.batches.Clear()
+	fmt.Fprintf(bv.batches, "[red]%s[-]\n", tview.Escape(err.Error()))
+}
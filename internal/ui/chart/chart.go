@@ -0,0 +1,125 @@
+package chart
+
+import (
+	"math"
+	"time"
+
+	"github.com/clemsau/kafe/internal/metrics"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// brailleDots are the bits of the braille dots of a cell, by column and row
+// from the top
+var brailleDots = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// Chart plots a series as a line drawn with braille characters, each cell
+// holding 2x4 dots, scaled from zero, or from the minimum value when
+// negative, to the maximum value
+type Chart struct {
+	*tview.Box
+	samples []metrics.Sample
+	from    time.Time
+	to      time.Time
+	color   tcell.Color
+}
+
+// NewChart creates an empty chart
+func NewChart() *Chart {
+	return &Chart{
+		Box:   tview.NewBox(),
+		color: tcell.ColorGreen,
+	}
+}
+
+// SetData sets the samples plotted between from and to
+func (c *Chart) SetData(samples []metrics.Sample, from, to time.Time) *Chart {
+	c.samples = samples
+	c.from = from
+	c.to = to
+	return c
+}
+
+// Draw draws the chart with the value axis on the left and the time axis
+// at the bottom
+func (c *Chart) Draw(screen tcell.Screen) {
+	c.Box.DrawForSubclass(screen, c)
+	x, y, width, height := c.GetInnerRect()
+
+	values := c.samples
+	series := make([]float64, len(values))
+	for i, sample := range values {
+		series[i] = sample.Value
+	}
+	bottom, top := valueRange(series)
+	if top <= bottom {
+		top = bottom + 1
+	}
+
+	labels := []string{FormatValue(top), FormatValue((top + bottom) / 2), FormatValue(bottom)}
+	labelWidth := 0
+	for _, label := range labels {
+		labelWidth = max(labelWidth, len(label))
+	}
+	labelWidth++
+
+	plotX, plotWidth, plotHeight := x+labelWidth, width-labelWidth, height-1
+	if plotWidth < 2 || plotHeight < 1 {
+		return
+	}
+
+	gray := tcell.ColorGray
+	tview.Print(screen, labels[0], x, y, labelWidth-1, tview.AlignRight, gray)
+	tview.Print(screen, labels[1], x, y+(plotHeight-1)/2, labelWidth-1, tview.AlignRight, gray)
+	tview.Print(screen, labels[2], x, y+plotHeight-1, labelWidth-1, tview.AlignRight, gray)
+
+	timeFormat := "15:04:05"
+	tview.Print(screen, c.from.Format(timeFormat), plotX, y+plotHeight, plotWidth, tview.AlignLeft, gray)
+	tview.Print(screen, c.from.Add(c.to.Sub(c.from)/2).Format(timeFormat), plotX, y+plotHeight, plotWidth, tview.AlignCenter, gray)
+	tview.Print(screen, c.to.Format(timeFormat), plotX, y+plotHeight, plotWidth, tview.AlignRight, gray)
+
+	if len(values) == 0 {
+		tview.Print(screen, "waiting for samples...", plotX, y+plotHeight/2, plotWidth, tview.AlignCenter, gray)
+		return
+	}
+
+	cells := make([][]rune, plotHeight)
+	for row := range cells {
+		cells[row] = make([]rune, plotWidth)
+	}
+	levels := plotHeight * 4
+	setDot := func(px, level int) {
+		row := levels - 1 - level
+		cells[row/4][px/2] |= brailleDots[px%2][row%4]
+	}
+
+	previous := -1
+	for px, v := range Resample(values, c.from, c.to, plotWidth*2) {
+		if math.IsNaN(v) {
+			previous = -1
+			continue
+		}
+		level := scale(v, bottom, top, levels)
+		low, high := level, level
+		if previous >= 0 {
+			// join the points vertically so steep changes stay visible
+			low, high = min(previous, level), max(previous, level)
+		}
+		for l := low; l <= high; l++ {
+			setDot(px, l)
+		}
+		previous = level
+	}
+
+	style := tcell.StyleDefault.Foreground(c.color)
+	for row, line := range cells {
+		for col, dots := range line {
+			if dots != 0 {
+				screen.SetContent(plotX+col, y+row, 0x2800+dots, nil, style)
+			}
+		}
+	}
+}
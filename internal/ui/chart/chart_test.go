@@ -0,0 +1,30 @@
+package chart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clemsau/kafe/internal/metrics"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestChartDrawsNegativeValues(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 10)
+
+	from := time.Now()
+	for _, values := range [][]float64{{-5, -1, -3}, {-2, 0, 4}} {
+		var samples []metrics.Sample
+		for i, v := range values {
+			samples = append(samples, metrics.Sample{At: from.Add(time.Duration(i) * time.Second), Value: v})
+		}
+
+		c := NewChart().SetData(samples, from, from.Add(3*time.Second))
+		c.SetRect(0, 0, 40, 10)
+		c.Draw(screen)
+	}
+}
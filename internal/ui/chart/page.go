@@ -0,0 +1,127 @@
+package chart
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/clemsau/kafe/internal/metrics"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// refreshInterval is how often the chart page is redrawn
+const refreshInterval = 2 * time.Second
+
+// Series returns the samples of a series over the last window
+type Series func(window time.Duration) []metrics.Sample
+
+// ChartPage shows the history of a series full screen, over a selectable
+// time window
+type ChartPage struct {
+	*tview.Flex
+	chart   *Chart
+	topBar  *topbar.TopBar
+	app     *ui.App
+	title   string
+	unit    string
+	series  Series
+	windows []time.Duration
+	window  int
+	cancel  context.CancelFunc
+}
+
+// NewChartPage creates a chart page of a series. The windows are the usual
+// rate windows, up to the history kept by the store.
+func NewChartPage(app *ui.App, history *metrics.Store, title, unit string, series Series) *ChartPage {
+	cp := &ChartPage{
+		chart:  NewChart(),
+		app:    app,
+		title:  title,
+		unit:   unit,
+		series: series,
+	}
+	for _, window := range metrics.Windows {
+		if window < history.Window() {
+			cp.windows = append(cp.windows, window)
+		}
+	}
+	cp.windows = append(cp.windows, history.Window())
+	cp.window = min(1, len(cp.windows)-1)
+
+	cp.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Esc", Description: "back"},
+		{Key: fmt.Sprintf("1-%d", len(cp.windows)), Description: "time window"},
+		{Key: "q", Description: "quit"},
+	})
+
+	cp.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(cp.topBar, cp.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(cp.chart, 0, 1, true)
+
+	cp.chart.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft)
+	cp.SetInputCapture(cp.handleInput)
+	return cp
+}
+
+func (cp *ChartPage) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEscape:
+		cp.Stop()
+		cp.app.RemovePage("chart")
+		return nil
+	case tcell.KeyRune:
+		if i := int(event.Rune() - '1'); i >= 0 && i < len(cp.windows) {
+			cp.window = i
+			cp.render()
+			return nil
+		}
+	}
+	return event
+}
+
+// Start draws the chart and keeps it up to date until the page is closed
+func (cp *ChartPage) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cp.cancel = cancel
+
+	cp.render()
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cp.app.QueueUpdateDraw(cp.render)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the chart
+func (cp *ChartPage) Stop() {
+	if cp.cancel != nil {
+		cp.cancel()
+	}
+}
+
+func (cp *ChartPage) render() {
+	window := cp.windows[cp.window]
+	samples := cp.series(window)
+	to := time.Now()
+	cp.chart.SetData(samples, to.Add(-window), to)
+
+	title := fmt.Sprintf(" %s - last %s ", cp.title, window)
+	if len(samples) > 0 {
+		title = fmt.Sprintf(" %s - last %s - now %s%s ", cp.title, window, FormatValue(samples[len(samples)-1].Value), cp.unit)
+	}
+	cp.chart.SetTitle(title)
+}
@@ -0,0 +1,107 @@
+// Package chart draws the history of the metrics as sparklines and charts.
+package chart
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/clemsau/kafe/internal/metrics"
+)
+
+const (
+	// SparklineWindow is the history a sparkline spans
+	SparklineWindow = 5 * time.Minute
+	// SparklineWidth is the number of characters of a table sparkline
+	SparklineWidth = 20
+)
+
+// sparks are the block characters of a sparkline, from lowest to highest
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Resample averages the samples in n buckets of equal duration between from
+// and to. Buckets without samples are NaN.
+func Resample(samples []metrics.Sample, from, to time.Time, n int) []float64 {
+	values := make([]float64, n)
+	counts := make([]int, n)
+	bucket := to.Sub(from) / time.Duration(n)
+	if bucket <= 0 {
+		bucket = 1
+	}
+
+	for _, sample := range samples {
+		if sample.At.Before(from) || sample.At.After(to) {
+			continue
+		}
+		i := min(int(sample.At.Sub(from)/bucket), n-1)
+		values[i] += sample.Value
+		counts[i]++
+	}
+	for i := range values {
+		if counts[i] == 0 {
+			values[i] = math.NaN()
+		} else {
+			values[i] /= float64(counts[i])
+		}
+	}
+	return values
+}
+
+// Sparkline renders values as block characters scaled from zero, or from
+// their minimum when negative, to their maximum, NaN values as blanks
+func Sparkline(values []float64) string {
+	bottom, top := valueRange(values)
+
+	var b strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			b.WriteRune(' ')
+		case top <= bottom:
+			b.WriteRune(sparks[0])
+		default:
+			b.WriteRune(sparks[scale(v, bottom, top, len(sparks))])
+		}
+	}
+	return b.String()
+}
+
+// valueRange returns the range values are scaled in, from zero or their
+// minimum when negative to their maximum, ignoring NaN values
+func valueRange(values []float64) (bottom, top float64) {
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			bottom = min(bottom, v)
+			top = max(top, v)
+		}
+	}
+	return bottom, top
+}
+
+// scale maps a value between bottom and top to one of n levels
+func scale(v, bottom, top float64, n int) int {
+	level := int(math.Round((v - bottom) / (top - bottom) * float64(n-1)))
+	return min(max(level, 0), n-1)
+}
+
+// SeriesSparkline renders the samples of the last SparklineWindow in width
+// characters
+func SeriesSparkline(samples []metrics.Sample, width int) string {
+	now := time.Now()
+	return Sparkline(Resample(samples, now.Add(-SparklineWindow), now, width))
+}
+
+// FormatValue formats a value compactly, e.g. 12.5, 3.2k or 1.1M
+func FormatValue(v float64) string {
+	switch {
+	case math.Abs(v) >= 1e9:
+		return fmt.Sprintf("%.1fG", v/1e9)
+	case math.Abs(v) >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case math.Abs(v) >= 1e3:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	default:
+		return fmt.Sprintf("%.1f", v)
+	}
+}
@@ -0,0 +1,53 @@
+package chart
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/clemsau/kafe/internal/metrics"
+)
+
+func TestResample(t *testing.T) {
+	from := time.Now()
+	samples := []metrics.Sample{
+		{At: from.Add(1 * time.Second), Value: 2},
+		{At: from.Add(2 * time.Second), Value: 4},
+		{At: from.Add(9 * time.Second), Value: 8},
+	}
+
+	values := Resample(samples, from, from.Add(10*time.Second), 2)
+	if values[0] != 3 || values[1] != 8 {
+		t.Errorf("got %v, want [3 8]", values)
+	}
+
+	values = Resample(samples, from, from.Add(10*time.Second), 5)
+	if !math.IsNaN(values[2]) {
+		t.Errorf("bucket without samples is %v, want NaN", values[2])
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := Sparkline([]float64{0, 4, 8, math.NaN()}); got != "▁▅█ " {
+		t.Errorf("got %q", got)
+	}
+	if got := Sparkline([]float64{0, 0}); got != "▁▁" {
+		t.Errorf("got %q for a flat series", got)
+	}
+}
+
+func TestSparklineNegative(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   string
+	}{
+		{[]float64{-8, -4, 0}, "▁▅█"},
+		{[]float64{-4, 0, 4, math.NaN()}, "▁▅█ "},
+		{[]float64{-2, -2}, "▁▁"},
+	}
+	for _, test := range tests {
+		if got := Sparkline(test.values); got != test.want {
+			t.Errorf("Sparkline(%v) = %q, want %q", test.values, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+// Package compacted shows the latest value of every key of a compacted
+// topic, as the topic materializes it.
+package compacted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	// refreshInterval is how often the table is redrawn during a scan
+	refreshInterval = 500 * time.Millisecond
+	// maxDisplayedKeys caps the rows of the table, the search narrows it down
+	maxDisplayedKeys = 10000
+	// maxValueWidth is how many characters of a value the table shows
+	maxValueWidth = 80
+)
+
+// KeyViewer browses the latest record of every key of a compacted topic
+type KeyViewer struct {
+	*tview.Flex
+	table     *tview.Table
+	searchBar *tview.InputField
+	topBar    *topbar.TopBar
+	app       *ui.App
+	client    *kafka.Client
+	topic     string
+	policy    string
+
+	mutex     sync.Mutex
+	keyTable  *kafka.KeyTable
+	cancel    context.CancelFunc
+	scanErr   error
+	displayed []models.Message
+}
+
+// NewKeyViewer creates a key view of the topic
+func NewKeyViewer(app *ui.App, client *kafka.Client, topic string) *KeyViewer {
+	kv := &KeyViewer{
+		table:     tview.NewTable().SetSelectable(true, false),
+		searchBar: tview.NewInputField(),
+		app:       app,
+		client:    client,
+		topic:     topic,
+	}
+
+	kv.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Esc", Description: "back to topics"},
+		{Key: "/", Description: "search keys"},
+		{Key: "Enter", Description: "record details"},
+		{Key: "r", Description: "scan again"},
+		{Key: "q", Description: "quit"},
+	})
+
+	kv.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(kv.topBar, kv.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(kv.searchBar, 3, 0, false).
+		AddItem(kv.table, 0, 1, true)
+
+	kv.setupUI()
+	return kv
+}
+
+func (kv *KeyViewer) setupUI() {
+	kv.searchBar.
+		SetLabel("/").
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle("Search keys").
+		SetTitleAlign(tview.AlignLeft)
+	kv.searchBar.SetChangedFunc(func(string) {
+		kv.render()
+	})
+	kv.searchBar.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc || event.Key() == tcell.KeyEnter {
+			kv.searchBar.SetBorderColor(tcell.ColorDefault)
+			kv.app.SetFocus(kv.table)
+			return nil
+		}
+		return event
+	})
+
+	kv.table.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft)
+	kv.table.SetFixed(1, 0)
+	kv.table.SetSelectedStyle(tcell.StyleDefault.
+		Background(tcell.ColorRoyalBlue).
+		Foreground(tcell.ColorWhite))
+	kv.table.SetSelectedFunc(func(row, _ int) {
+		kv.showDetails(row)
+	})
+
+	kv.SetInputCapture(kv.handleInput)
+}
+
+func (kv *KeyViewer) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	if kv.searchBar.HasFocus() {
+		return event
+	}
+
+	switch event.Key() {
+	case tcell.KeyEscape:
+		kv.Stop()
+		kv.app.RemovePage("keys")
+		return nil
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case '/':
+			kv.searchBar.SetBorderColor(tcell.ColorYellow)
+			kv.app.SetFocus(kv.searchBar)
+			return nil
+		case 'r':
+			if err := kv.Start(); err != nil {
+				kv.table.SetTitle(fmt.Sprintf(" Keys - %s - %v ", kv.topic, err))
+			}
+			return nil
+		}
+	}
+	return event
+}
+
+// Start scans the topic from the earliest records, stopping any running scan
+func (kv *KeyViewer) Start() error {
+	kv.Stop()
+
+	if config, err := kv.client.TopicConfig(kv.topic, "cleanup.policy"); err == nil {
+		kv.policy = config["cleanup.policy"]
+	}
+
+	keyTable, err := kv.client.NewKeyTable(kv.topic)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	kv.mutex.Lock()
+	kv.keyTable = keyTable
+	kv.cancel = cancel
+	kv.scanErr = nil
+	kv.mutex.Unlock()
+
+	go func() {
+		err := keyTable.Run(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			kv.mutex.Lock()
+			if kv.keyTable == keyTable {
+				kv.scanErr = err
+			}
+			kv.mutex.Unlock()
+		}
+		kv.app.QueueUpdateDraw(kv.render)
+	}()
+	go kv.refreshLoop(ctx, keyTable)
+
+	kv.render()
+	return nil
+}
+
+// Stop cancels the running scan, if any
+func (kv *KeyViewer) Stop() {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+	if kv.cancel != nil {
+		kv.cancel()
+		kv.cancel = nil
+	}
+}
+
+func (kv *KeyViewer) refreshLoop(ctx context.Context, keyTable *kafka.KeyTable) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			kv.app.QueueUpdateDraw(kv.render)
+			if keyTable.Progress().Done {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// render redraws the keys matching the search, keeping the selected key
+func (kv *KeyViewer) render() {
+	kv.mutex.Lock()
+	keyTable, scanErr := kv.keyTable, kv.scanErr
+	kv.mutex.Unlock()
+	if keyTable == nil {
+		return
+	}
+
+	selected := ""
+	if row, _ := kv.table.GetSelection(); row > 0 && row-1 < len(kv.displayed) {
+		selected = kv.displayed[row-1].KeyText()
+	}
+
+	search := strings.ToLower(kv.searchBar.GetText())
+	var displayed []models.Message
+	matched := 0
+	for _, msg := range keyTable.Keys() {
+		if search != "" && !strings.Contains(strings.ToLower(msg.KeyText()), search) {
+			continue
+		}
+		matched++
+		if len(displayed) < maxDisplayedKeys {
+			displayed = append(displayed, msg)
+		}
+	}
+	kv.displayed = displayed
+
+	kv.table.Clear()
+	for col, header := range []string{"Key", "Value", "Partition", "Offset", "Timestamp"} {
+		kv.table.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	selectRow := 1
+	for i, msg := range displayed {
+		if msg.KeyText() == selected {
+			selectRow = i + 1
+		}
+		cells := []string{
+			tview.Escape(msg.KeyText()),
+			tview.Escape(truncate(msg.ValueText(), maxValueWidth)),
+			fmt.Sprintf("%d", msg.Partition),
+			fmt.Sprintf("%d", msg.Offset),
+			msg.Timestamp.Format("2006-01-02 15:04:05"),
+		}
+		for col, content := range cells {
+			cell := tview.NewTableCell(content).SetExpansion(1)
+			if col == 1 {
+				cell.SetExpansion(3)
+			}
+			if col == 0 && msg.DecodeErr != nil {
+				cell.SetTextColor(tcell.ColorRed)
+			}
+			kv.table.SetCell(i+1, col, cell)
+		}
+	}
+	if len(displayed) > 0 {
+		kv.table.Select(selectRow, 0)
+	}
+
+	kv.table.SetTitle(kv.title(keyTable, matched, scanErr))
+}
+
+func (kv *KeyViewer) title(keyTable *kafka.KeyTable, matched int, scanErr error) string {
+	live, deleted := keyTable.Counts()
+	parts := []string{"Keys", tview.Escape(kv.topic), fmt.Sprintf("%d keys, %d deleted", live, deleted)}
+	if kv.searchBar.GetText() != "" {
+		parts = append(parts, fmt.Sprintf("%d matching", matched))
+	}
+	if matched > maxDisplayedKeys {
+		parts = append(parts, fmt.Sprintf("first %d shown", maxDisplayedKeys))
+	}
+
+	progress := keyTable.Progress()
+	if scanErr != nil {
+		parts = append(parts, fmt.Sprintf("[red]scan failed after %d records: %s[-]", progress.Scanned, tview.Escape(scanErr.Error())))
+	} else if progress.Done {
+		parts = append(parts, fmt.Sprintf("scanned %d records in %s", progress.Scanned, progress.Elapsed.Round(time.Millisecond)))
+	} else {
+		parts = append(parts, fmt.Sprintf("scanning %d/%d", progress.Scanned, progress.Total))
+	}
+	for _, partition := range progress.Partitions {
+		if partition.Err != nil {
+			parts = append(parts, fmt.Sprintf("[red]partition %d: %s[-]", partition.Partition, tview.Escape(partition.Err.Error())))
+		}
+	}
+	if kv.policy != "" && !strings.Contains(kv.policy, "compact") {
+		parts = append(parts, fmt.Sprintf("[yellow]cleanup.policy=%s, not compacted[-]", kv.policy))
+	}
+	return " " + strings.Join(parts, " - ") + " "
+}
+
+// showDetails displays the full latest record of the key at row
+func (kv *KeyViewer) showDetails(row int) {
+	if row < 1 || row-1 >= len(kv.displayed) {
+		return
+	}
+	msg := kv.displayed[row-1]
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "[yellow]Key:[-] %s\n", tview.Escape(msg.KeyText()))
+	fmt.Fprintf(&text, "[yellow]Partition:[-] %d  [yellow]Offset:[-] %d  [yellow]Timestamp:[-] %s\n",
+		msg.Partition, msg.Offset, msg.Timestamp.Format(time.RFC3339Nano))
+	for _, header := range msg.Headers {
+		fmt.Fprintf(&text, "[yellow]Header %s:[-] %s\n", tview.Escape(header.Key), tview.Escape(string(header.Value)))
+	}
+	if msg.DecodeErr != nil {
+		fmt.Fprintf(&text, "[red]Decode error: %s[-]\n", tview.Escape(msg.DecodeErr.Error()))
+	}
+	fmt.Fprintf(&text, "\n%s\n", tview.Escape(msg.ValueText()))
+
+	details := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true).
+		SetText(text.String())
+	details.SetBorder(true).
+		SetTitle(" Latest record (Esc to close) ").
+		SetTitleAlign(tview.AlignLeft)
+	details.SetDoneFunc(func(tcell.Key) {
+		kv.app.RemovePage("key-details")
+	})
+
+	kv.app.AddPage("key-details", details, true)
+}
+
+func truncate(text string, width int) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	if len([]rune(text)) <= width {
+		return text
+	}
+	return string([]rune(text)[:width-1]) + "…"
+}
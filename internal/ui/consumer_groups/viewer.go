@@ -6,11 +6,14 @@ import (
 	"time"
 
 	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/metrics"
 	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/rules"
 	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/chart"
 	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/dialog"
 	"github.com/clemsau/kafe/internal/ui/topbar"
-	"github.com/clemsau/kafe/internal/ui/utils"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -21,6 +24,7 @@ type GroupViewer struct {
 	client     *kafka.Client
 	topic      string
 	cache      *models.ConsumerGroupCache
+	history    *metrics.Store
 	updateChan chan []models.ConsumerGroupInfo
 	headers    []string
 	searchBar  *tview.InputField
@@ -28,19 +32,23 @@ type GroupViewer struct {
 	layout     *tview.Flex
 }
 
-func NewGroupViewer(app *ui.App, client *kafka.Client, topic string) *tview.Flex {
+func NewGroupViewer(app *ui.App, client *kafka.Client, topic string, history *metrics.Store) *tview.Flex {
 	viewer := &GroupViewer{
 		Table:      tview.NewTable().SetSelectable(true, false),
 		app:        app,
 		client:     client,
 		topic:      topic,
 		cache:      models.NewConsumerGroupCache(),
+		history:    history,
 		updateChan: make(chan []models.ConsumerGroupInfo),
 		headers: []string{
 			"Group ID",
 			"Members",
 			"Total Lag",
+			"Time behind",
 			"Status",
+			"Consume rate (1m)",
+			"Lag (5m)",
 		},
 	}
 
@@ -68,7 +76,10 @@ func NewGroupViewer(app *ui.App, client *kafka.Client, topic string) *tview.Flex
 
 	viewer.topBar = topbar.NewTopBar([]controls.Control{
 		{Key: "Esc", Description: "back to topics"},
+		{Key: "Enter", Description: "partition lags"},
 		{Key: "/", Description: "search"},
+		{Key: "h", Description: "lag chart"},
+		{Key: "W", Description: "watch/unwatch"},
 		{Key: "q", Description: "quit"},
 	})
 
@@ -108,11 +119,21 @@ func (v *GroupViewer) setupUI() {
 		case tcell.KeyEscape:
 			v.app.RemovePage("consumer-groups")
 			return nil
+		case tcell.KeyEnter:
+			v.showPartitions()
+			return nil
 		case tcell.KeyRune:
-			if event.Rune() == '/' {
+			switch event.Rune() {
+			case '/':
 				v.searchBar.SetBorderColor(tcell.ColorYellow)
 				v.app.SetFocus(v.searchBar)
 				return nil
+			case 'h':
+				v.showChart()
+				return nil
+			case 'W':
+				v.toggleWatch()
+				return nil
 			}
 		}
 		return event
@@ -136,7 +157,10 @@ func (v *GroupViewer) updateTable(groups []models.ConsumerGroupInfo) {
 			group.ID,
 			fmt.Sprintf("%d", group.Members),
 			fmt.Sprintf("%d", group.TotalLag),
+			FormatTimeLag(group.TimeLag, group.TimeLagKnown),
 			group.Status,
+			v.consumeRate(group),
+			chart.SeriesSparkline(v.history.Samples(metrics.GroupKey(metrics.GroupLag, group.ID, group.Topic), chart.SparklineWindow), chart.SparklineWidth),
 		}
 
 		for col, content := range cells {
@@ -144,8 +168,14 @@ func (v *GroupViewer) updateTable(groups []models.ConsumerGroupInfo) {
 				SetAlign(tview.AlignLeft).
 				SetExpansion(1)
 
-			if col == 3 {
-				cell.SetTextColor(utils.GetConsumerGroupStatusColor(group.Status))
+			if col == 0 && v.app.WatchList().HasGroup(group.ID, group.Topic) {
+				cell.SetTextColor(ui.WatchedColor)
+			}
+			if col == 4 {
+				cell.SetTextColor(v.app.Rules().Color(group.Status))
+			}
+			if col == 6 {
+				cell.SetTextColor(tcell.ColorYellow).SetExpansion(0)
 			}
 
 			v.SetCell(row+1, col, cell)
@@ -167,28 +197,183 @@ func (v *GroupViewer) startMonitoring() {
 	}()
 }
 
+// monitorGroups shows the consumer groups of the topic polled by the
+// metrics store, which keeps sampling them once the viewer is closed
 func (v *GroupViewer) monitorGroups() {
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	v.fetchAndUpdateGroups()
+	v.history.TrackGroups(v.topic)
 
+	var last time.Time
 	for range ticker.C {
-		v.fetchAndUpdateGroups()
+		poll, ok := v.history.Groups(v.topic)
+		if !ok || !poll.At.After(last) {
+			continue
+		}
+		last = poll.At
+		if poll.Err == nil {
+			v.updateGroups(poll.Groups)
+		}
+	}
+}
+
+func (v *GroupViewer) updateGroups(groups []models.ConsumerGroupInfo) {
+	ids := make([]string, 0, len(groups))
+	consuming := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		Evaluate(v.app, v.history, &group)
+		v.cache.UpsertGroup(group)
+		ids = append(ids, group.ID)
+		consuming[group.ID] = true
+	}
+
+	// groups without members on the topic are not returned, they stay
+	// cached while listed on the cluster with committed offsets on it
+	var idle []string
+	for _, group := range v.cache.GetSortedGroups() {
+		if !consuming[group.ID] {
+			idle = append(idle, group.ID)
+		}
+	}
+	if retained, err := v.client.RetainedGroups(v.topic, idle); err == nil {
+		for _, id := range v.cache.Reconcile(append(ids, retained...)) {
+			v.history.RemoveGroup(id, v.topic)
+		}
 	}
+
+	v.updateChan <- v.cache.GetSortedGroups()
 }
 
-func (v *GroupViewer) fetchAndUpdateGroups() {
-	groups, err := v.client.GetConsumerGroups(v.topic)
+// toggleWatch adds the selected group to the watch list, or removes it
+func (v *GroupViewer) toggleWatch() {
+	selectedRow, _ := v.GetSelection()
+	if selectedRow <= 0 {
+		return
+	}
+
+	cell := v.GetCell(selectedRow, 0)
+	watched, err := v.app.WatchList().ToggleGroup(cell.Text, v.topic)
 	if err != nil {
+		dialog.ShowError(v.app, err.Error())
+	}
+	if watched {
+		cell.SetTextColor(ui.WatchedColor)
+	} else {
+		cell.SetTextColor(tcell.ColorWhite)
+	}
+}
+
+// showChart opens the lag chart of the selected group
+func (v *GroupViewer) showChart() {
+	selectedRow, _ := v.GetSelection()
+	if selectedRow <= 0 {
 		return
 	}
 
-	for _, group := range groups {
-		v.cache.UpsertGroup(group)
+	group := v.GetCell(selectedRow, 0).Text
+	key := metrics.GroupKey(metrics.GroupLag, group, v.topic)
+	page := chart.NewChartPage(v.app, v.history, "Lag - "+group+" - "+v.topic, " messages", func(window time.Duration) []metrics.Sample {
+		return v.history.Samples(key, window)
+	})
+	v.app.AddPage("chart", page, true)
+	page.Start()
+}
+
+// Evaluate completes the time lag of a polled group and sets its status
+// from the rules
+func Evaluate(app *ui.App, history *metrics.Store, group *models.ConsumerGroupInfo) {
+	lagKey := metrics.GroupKey(metrics.GroupLag, group.ID, group.Topic)
+	estimateTimeLag(history, group)
+	group.Status = app.Rules().GroupStatus(*group, rules.GroupMetrics{
+		LagGrowth: func(window time.Duration) (float64, bool) {
+			return history.Trend(lagKey, window)
+		},
+	})
+}
+
+// estimateTimeLag completes the time lag of a group when the timestamps of
+// some records to consume could not be read, dividing the lag by the produce
+// rate of the topic over the last 5 minutes
+func estimateTimeLag(history *metrics.Store, group *models.ConsumerGroupInfo) {
+	if group.TimeLagKnown || group.TotalLag <= 0 {
+		return
+	}
+	rate, ok := history.Rate(metrics.TopicKey(metrics.TopicOffsets, group.Topic), 5*time.Minute)
+	if !ok || rate <= 0 {
+		return
 	}
 
-	v.updateChan <- v.cache.GetSortedGroups()
+	estimate := time.Duration(float64(group.TotalLag) / rate * float64(time.Second))
+	group.TimeLag = max(group.TimeLag, estimate)
+}
+
+// FormatTimeLag formats a time lag to the second, prefixed with ~ when it
+// is estimated
+func FormatTimeLag(timeLag time.Duration, known bool) string {
+	text := timeLag.Round(time.Second).String()
+	if !known {
+		text = "~" + text
+	}
+	return text
+}
+
+// showPartitions opens the lag of each partition for the selected group
+func (v *GroupViewer) showPartitions() {
+	selectedRow, _ := v.GetSelection()
+	if selectedRow <= 0 {
+		return
+	}
+
+	group, ok := v.cache.Get(v.GetCell(selectedRow, 0).Text)
+	if !ok {
+		return
+	}
+
+	table := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	for col, header := range []string{"Partition", "Committed", "Newest", "Lag", "Time behind"} {
+		table.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+	for row, p := range group.Partitions {
+		timeLag := "?"
+		if p.TimeLagKnown {
+			timeLag = FormatTimeLag(p.TimeLag, true)
+		}
+		cells := []string{
+			fmt.Sprintf("%d", p.Partition),
+			fmt.Sprintf("%d", p.Committed),
+			fmt.Sprintf("%d", p.Newest),
+			fmt.Sprintf("%d", p.Lag()),
+			timeLag,
+		}
+		for col, content := range cells {
+			table.SetCell(row+1, col, tview.NewTableCell(content).SetExpansion(1))
+		}
+	}
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Partitions - %s - %s (Esc to close) ", group.ID, v.topic)).
+		SetTitleAlign(tview.AlignLeft)
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			v.app.RemovePage("group-partitions")
+			return nil
+		}
+		return event
+	})
+	v.app.AddPage("group-partitions", table, true)
+}
+
+// consumeRate returns the committed offsets per second over the last minute
+func (v *GroupViewer) consumeRate(group models.ConsumerGroupInfo) string {
+	rate, ok := v.history.Rate(metrics.GroupKey(metrics.GroupCommitted, group.ID, group.Topic), time.Minute)
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", rate)
 }
 
 func (v *GroupViewer) applyFilter(filterText string) {
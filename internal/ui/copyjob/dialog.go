@@ -0,0 +1,249 @@
+// Package copyjob copies a range of a topic to another topic, possibly on
+// another cluster context.
+package copyjob
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/filter"
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// CopyDialog copies the messages of a topic range matching a filter to a
+// destination topic
+type CopyDialog struct {
+	*tview.Flex
+	form     *tview.Form
+	status   *tview.TextView
+	topBar   *topbar.TopBar
+	app      *ui.App
+	client   *kafka.Client
+	topic    string
+	contexts []string
+	cancel   context.CancelFunc
+	mutex    sync.Mutex
+}
+
+// NewCopyDialog creates a copy page for the topic
+func NewCopyDialog(app *ui.App, client *kafka.Client, topic string) *CopyDialog {
+	cd := &CopyDialog{
+		form: tview.NewForm(),
+		status: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true).
+			SetWrap(true),
+		app:    app,
+		client: client,
+		topic:  topic,
+	}
+
+	cd.contexts = []string{"current cluster"}
+	for _, ctx := range app.Contexts() {
+		if ctx.Name != app.CurrentContext() {
+			cd.contexts = append(cd.contexts, ctx.Name)
+		}
+	}
+
+	cd.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Tab", Description: "next field"},
+		{Key: "Esc", Description: "close"},
+	})
+
+	cd.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(cd.topBar, cd.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(cd.form, 21, 0, true).
+		AddItem(cd.status, 0, 1, false)
+
+	cd.setupUI()
+	return cd
+}
+
+func (cd *CopyDialog) setupUI() {
+	cd.form.
+		AddDropDown("Destination", cd.contexts, 0, nil).
+		AddInputField("Topic", "", 40, nil, nil).
+		AddInputField("From", "earliest", 30, nil, nil).
+		AddInputField("To", "latest", 30, nil, nil).
+		AddInputField("Filter", "", 0, nil, nil).
+		AddCheckbox("Keep partitions", false, nil).
+		AddCheckbox("Keep timestamps", false, nil).
+		AddInputField("Rate (msg/s)", "0", 10, tview.InputFieldFloat, nil).
+		AddCheckbox("Idempotent", false, nil).
+		AddButton("Start", cd.start).
+		AddButton("Cancel", cd.Stop).
+		AddButton("Close", cd.close).
+		SetCancelFunc(cd.close).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle(fmt.Sprintf(" Copy - %s (time, earliest, latest or @offset) ", cd.topic)).
+		SetTitleAlign(tview.AlignLeft)
+
+	cd.status.SetBorder(true).
+		SetTitle(" Result ").
+		SetTitleAlign(tview.AlignLeft)
+	fmt.Fprint(cd.status, "[gray]Keys, values and headers are copied as is. The end of the range is captured when the copy starts.[-]\n")
+}
+
+func (cd *CopyDialog) text(label string) string {
+	return cd.form.GetFormItemByLabel(label).(*tview.InputField).GetText()
+}
+
+func (cd *CopyDialog) checked(label string) bool {
+	return cd.form.GetFormItemByLabel(label).(*tview.Checkbox).IsChecked()
+}
+
+// start reads the form and copies the messages in the background
+func (cd *CopyDialog) start() {
+	destIndex, destName := cd.form.GetFormItemByLabel("Destination").(*tview.DropDown).GetCurrentOption()
+	destTopic := cd.text("Topic")
+	if destTopic == "" {
+		destTopic = cd.topic
+	}
+	if destIndex == 0 && destTopic == cd.topic {
+		cd.report(fmt.Errorf("the destination is the source topic"))
+		return
+	}
+
+	now := time.Now()
+	from, err := kafka.ParsePosition(cd.text("From"), now)
+	if err != nil {
+		cd.report(fmt.Errorf("from: %w", err))
+		return
+	}
+	to, err := kafka.ParsePosition(cd.text("To"), now)
+	if err != nil {
+		cd.report(fmt.Errorf("to: %w", err))
+		return
+	}
+	f, err := filter.ParseAt(cd.text("Filter"), now)
+	if err != nil {
+		cd.report(fmt.Errorf("filter: %w", err))
+		return
+	}
+	rate, err := strconv.ParseFloat(cd.text("Rate (msg/s)"), 64)
+	if err != nil || rate < 0 {
+		cd.report(fmt.Errorf("invalid rate"))
+		return
+	}
+
+	dest := cd.client
+	if destIndex > 0 {
+		for _, ctx := range cd.app.Contexts() {
+			if ctx.Name == destName {
+				dest, err = kafka.NewClient(ctx.Brokers, nil)
+				break
+			}
+		}
+		if err != nil {
+			cd.report(err)
+			return
+		}
+	}
+	closeDest := func() {
+		if dest != cd.client {
+			dest.Close()
+		}
+	}
+
+	job, err := cd.client.NewCopyJob(cd.topic, dest, destTopic, kafka.CopyOptions{
+		BulkOptions: kafka.BulkOptions{
+			ProducerOptions: kafka.ProducerOptions{
+				Partitioner: kafka.Partitioners[0],
+				Compression: kafka.Compressions[0],
+				Idempotent:  cd.checked("Idempotent"),
+			},
+			Rate: rate,
+		},
+		From:           from,
+		To:             to,
+		Filter:         f,
+		KeepPartitions: cd.checked("Keep partitions"),
+		KeepTimestamps: cd.checked("Keep timestamps"),
+	})
+	if err != nil {
+		closeDest()
+		cd.report(err)
+		return
+	}
+
+	cd.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	cd.mutex.Lock()
+	cd.cancel = cancel
+	cd.mutex.Unlock()
+
+	cd.status.Clear()
+	fmt.Fprintf(cd.status, "[yellow]Copying to %s on %s...[-]\n", tview.Escape(destTopic), tview.Escape(destName))
+
+	go func() {
+		defer closeDest()
+		result, err := job.Run(ctx, func(progress kafka.BulkResult) {
+			scan := job.Progress()
+			delivered, failed := progress.Delivered, progress.Failed
+			cd.app.QueueUpdateDraw(func() {
+				cd.status.SetTitle(fmt.Sprintf(" Result - scanned %d/%d, delivered %d, failed %d ",
+					scan.Scanned, scan.Total, delivered, failed))
+			})
+		})
+		scan := job.Progress()
+		cd.app.QueueUpdateDraw(func() {
+			cd.showSummary(scan, result, err)
+		})
+	}()
+}
+
+// Stop cancels the running copy, if any
+func (cd *CopyDialog) Stop() {
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	if cd.cancel != nil {
+		cd.cancel()
+		cd.cancel = nil
+	}
+}
+
+func (cd *CopyDialog) showSummary(scan kafka.SearchProgress, result kafka.BulkResult, err error) {
+	cd.status.Clear()
+	cd.status.SetTitle(" Result ")
+
+	if err != nil {
+		fmt.Fprintf(cd.status, "[red]Stopped: %s[-]\n", tview.Escape(err.Error()))
+	}
+	for _, partition := range scan.Partitions {
+		if partition.Err != nil {
+			fmt.Fprintf(cd.status, "[red]Partition %d: %s[-]\n", partition.Partition, tview.Escape(partition.Err.Error()))
+		}
+	}
+	fmt.Fprintf(cd.status, "Scanned: %d\nMatched: %d\n[green]Delivered: %d[-]\n", scan.Scanned, scan.Matched, result.Delivered)
+	if result.Failed == 0 {
+		fmt.Fprintf(cd.status, "Failed: 0\nElapsed: %s\n", result.Elapsed.Round(time.Millisecond))
+		return
+	}
+
+	fmt.Fprintf(cd.status, "[red]Failed: %d[-]\nElapsed: %s\n\n[yellow]Failure reasons:[-]\n", result.Failed, result.Elapsed.Round(time.Millisecond))
+	for _, reason := range result.SortedReasons() {
+		fmt.Fprintf(cd.status, "  %6d  %s\n", result.Reasons[reason], tview.Escape(reason))
+	}
+}
+
+func (cd *CopyDialog) report(err error) {
+	cd.status.Clear()
+	fmt.Fprintf(cd.status, "[red]%s[-]\n", tview.Escape(err.Error()))
+}
+
+func (cd *CopyDialog) close() {
+	cd.Stop()
+	cd.app.RemovePage("copy")
+}
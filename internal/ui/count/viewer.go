@@ -0,0 +1,196 @@
+// Package count counts the records of a topic exactly, as opposed to the
+// offset span shown on the topics table.
+package count
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// refreshInterval is how often the table is redrawn during a count
+const refreshInterval = 500 * time.Millisecond
+
+// CountViewer shows the exact record count of each partition of a topic
+type CountViewer struct {
+	*tview.Flex
+	table  *tview.Table
+	topBar *topbar.TopBar
+	app    *ui.App
+	client *kafka.Client
+	topic  string
+
+	mutex   sync.Mutex
+	counter *kafka.RecordCounter
+	cancel  context.CancelFunc
+}
+
+// NewCountViewer creates a count page for the topic
+func NewCountViewer(app *ui.App, client *kafka.Client, topic string) *CountViewer {
+	cv := &CountViewer{
+		table:  tview.NewTable().SetSelectable(true, false),
+		app:    app,
+		client: client,
+		topic:  topic,
+	}
+
+	cv.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Esc", Description: "back to topics"},
+		{Key: "r", Description: "count again"},
+		{Key: "q", Description: "quit"},
+	})
+
+	cv.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(cv.topBar, cv.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(cv.table, 0, 1, true)
+
+	cv.setupUI()
+	return cv
+}
+
+func (cv *CountViewer) setupUI() {
+	cv.table.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft)
+	cv.table.SetFixed(1, 0)
+	cv.table.SetSelectedStyle(tcell.StyleDefault.
+		Background(tcell.ColorRoyalBlue).
+		Foreground(tcell.ColorWhite))
+
+	cv.SetInputCapture(cv.handleInput)
+}
+
+func (cv *CountViewer) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEscape:
+		cv.Stop()
+		cv.app.RemovePage("count")
+		return nil
+	case tcell.KeyRune:
+		if event.Rune() == 'r' {
+			if err := cv.Start(); err != nil {
+				cv.table.SetTitle(fmt.Sprintf(" Record count - %s - %v ", cv.topic, err))
+			}
+			return nil
+		}
+	}
+	return event
+}
+
+// Start counts the records of the topic, stopping any running count
+func (cv *CountViewer) Start() error {
+	cv.Stop()
+
+	counter, err := cv.client.NewRecordCounter(cv.topic)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cv.mutex.Lock()
+	cv.counter = counter
+	cv.cancel = cancel
+	cv.mutex.Unlock()
+
+	go func() {
+		counter.Run(ctx)
+		cv.app.QueueUpdateDraw(cv.render)
+	}()
+	go cv.refreshLoop(ctx, counter)
+
+	cv.render()
+	return nil
+}
+
+// Stop cancels the running count, if any
+func (cv *CountViewer) Stop() {
+	cv.mutex.Lock()
+	defer cv.mutex.Unlock()
+	if cv.cancel != nil {
+		cv.cancel()
+		cv.cancel = nil
+	}
+}
+
+func (cv *CountViewer) refreshLoop(ctx context.Context, counter *kafka.RecordCounter) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cv.app.QueueUpdateDraw(cv.render)
+			if counter.Progress().Done {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// render redraws the count of every partition. Gaps are the offsets of the
+// scanned range holding no record, removed by compaction or retention.
+func (cv *CountViewer) render() {
+	cv.mutex.Lock()
+	counter := cv.counter
+	cv.mutex.Unlock()
+	if counter == nil {
+		return
+	}
+	count := counter.Progress()
+
+	cv.table.Clear()
+	for col, header := range []string{"Partition", "Offset span", "Scanned", "Records", "Aborted", "Markers", "Gaps", "Status"} {
+		cv.table.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	for row, p := range count.Partitions {
+		status, color := "Counting", tcell.ColorYellow
+		switch {
+		case p.Err != nil:
+			status, color = p.Err.Error(), tcell.ColorRed
+		case p.Done:
+			status, color = "Done", tcell.ColorGreen
+		}
+
+		cells := []string{
+			fmt.Sprintf("%d", p.Partition),
+			fmt.Sprintf("%d", p.Span()),
+			fmt.Sprintf("%d", p.Scanned),
+			fmt.Sprintf("%d", p.Records),
+			fmt.Sprintf("%d", p.Aborted),
+			fmt.Sprintf("%d", p.Markers),
+			fmt.Sprintf("%d", p.Scanned-p.Records-p.Markers),
+			status,
+		}
+		for col, content := range cells {
+			cell := tview.NewTableCell(tview.Escape(content)).SetExpansion(1)
+			if col == 7 {
+				cell.SetTextColor(color)
+			}
+			cv.table.SetCell(row+1, col, cell)
+		}
+	}
+
+	title := fmt.Sprintf(" Record count - %s - %d records in an offset span of %d (%d aborted, %d markers)",
+		tview.Escape(cv.topic), count.Records, count.Span, count.Aborted, count.Markers)
+	if count.Done {
+		title += fmt.Sprintf(" - done in %s ", count.Elapsed.Round(time.Millisecond))
+	} else {
+		title += fmt.Sprintf(" - scanned %d/%d ", count.Scanned, count.Span)
+	}
+	cv.table.SetTitle(title)
+}
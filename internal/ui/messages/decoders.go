@@ -0,0 +1,137 @@
+package messages
+
+import (
+	"fmt"
+
+	"github.com/clemsau/kafe/internal/config"
+	"github.com/clemsau/kafe/internal/deserialize"
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// topicDeserializers are the deserializers of a topic's keys and values
+type topicDeserializers struct {
+	key   deserialize.Deserializer
+	value deserialize.Deserializer
+}
+
+// deserializersOf returns the deserializers decoding the client's
+// messages, the built-in ones when the client has none
+func deserializersOf(client *kafka.Client) *deserialize.Deserializers {
+	if deserializers, ok := client.Decoder().(*deserialize.Deserializers); ok {
+		return deserializers
+	}
+	deserializers, _ := deserialize.NewDeserializers(config.Context{})
+	return deserializers
+}
+
+// decode decodes a consumed message with the viewer's deserializers of its topic
+func (mv *MessageViewer) decode(msg *models.Message) {
+	mv.mutex.Lock()
+	d := mv.topicDeserializers[msg.Topic]
+	mv.mutex.Unlock()
+
+	deserialize.Decode(msg, d.key, d.value)
+}
+
+// SetDeserializers changes the deserializers of a topic. The buffered
+// messages of the topic are decoded again, consumers keep running.
+func (mv *MessageViewer) SetDeserializers(topic string, key, value deserialize.Deserializer) {
+	mv.mutex.Lock()
+	mv.topicDeserializers[topic] = topicDeserializers{key: key, value: value}
+	mv.rebuild = true
+	mv.mutex.Unlock()
+
+	mv.buffer.Update(func(msg *models.Message) {
+		if msg.Topic == topic {
+			deserialize.Decode(msg, key, value)
+		}
+	})
+	mv.render()
+}
+
+// deserializersTitle describes the deserializers that are not auto, for
+// the title. Must be called with the mutex held.
+func (mv *MessageViewer) deserializersTitle() []string {
+	var parts []string
+	for _, topic := range mv.topics {
+		d := mv.topicDeserializers[topic]
+		if d.key.Name() == deserialize.DeserializerAuto && d.value.Name() == deserialize.DeserializerAuto {
+			continue
+		}
+		part := fmt.Sprintf("key %s, value %s", d.key.Name(), d.value.Name())
+		if len(mv.topics) > 1 {
+			part = tview.Escape(topic) + " " + part
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// showDeserializerForm asks for the key and value deserializers of a topic
+func (mv *MessageViewer) showDeserializerForm() {
+	names := mv.deserializers.Names()
+	index := func(name string) int {
+		for i, n := range names {
+			if n == name {
+				return i
+			}
+		}
+		return 0
+	}
+	current := func(topic string) (int, int) {
+		mv.mutex.Lock()
+		defer mv.mutex.Unlock()
+		d := mv.topicDeserializers[topic]
+		return index(d.key.Name()), index(d.value.Name())
+	}
+
+	form := tview.NewForm()
+	keyIndex, valueIndex := current(mv.topics[0])
+	if len(mv.topics) > 1 {
+		form.AddDropDown("Topic", mv.topics, 0, func(topic string, _ int) {
+			key, value := form.GetFormItemByLabel("Key"), form.GetFormItemByLabel("Value")
+			if key == nil || value == nil {
+				return
+			}
+			keyIndex, valueIndex := current(topic)
+			key.(*tview.DropDown).SetCurrentOption(keyIndex)
+			value.(*tview.DropDown).SetCurrentOption(valueIndex)
+		})
+	}
+	form.
+		AddDropDown("Key", names, keyIndex, nil).
+		AddDropDown("Value", names, valueIndex, nil).
+		AddButton("Apply", func() {
+			topic := mv.topics[0]
+			if item := form.GetFormItemByLabel("Topic"); item != nil {
+				_, topic = item.(*tview.DropDown).GetCurrentOption()
+			}
+			_, key := form.GetFormItemByLabel("Key").(*tview.DropDown).GetCurrentOption()
+			_, value := form.GetFormItemByLabel("Value").(*tview.DropDown).GetCurrentOption()
+			mv.app.RemovePage("decoders")
+
+			keyDeserializer, _ := mv.deserializers.Get(key)
+			valueDeserializer, _ := mv.deserializers.Get(value)
+			mv.SetDeserializers(topic, keyDeserializer, valueDeserializer)
+		}).
+		AddButton("Cancel", func() {
+			mv.app.RemovePage("decoders")
+		}).
+		SetCancelFunc(func() {
+			mv.app.RemovePage("decoders")
+		}).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle(" Decoders ").
+		SetTitleAlign(tview.AlignLeft)
+
+	height := 9
+	if len(mv.topics) > 1 {
+		height += 2
+	}
+	mv.app.AddPage("decoders", centered(form, 60, height), true)
+}
@@ -0,0 +1,182 @@
+package messages
+
+import (
+	"fmt"
+
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/records"
+	"github.com/clemsau/kafe/internal/ui/dialog"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// toggleExport stops the running export or asks where to start one
+func (mv *MessageViewer) toggleExport() {
+	mv.mutex.Lock()
+	exporting := mv.exporter != nil
+	mv.mutex.Unlock()
+
+	if exporting {
+		mv.stopExport()
+		return
+	}
+	mv.showExportForm()
+}
+
+// showExportForm asks for the export file and format
+func (mv *MessageViewer) showExportForm() {
+	formats := make([]string, 0, len(records.ExportFormats))
+	for _, format := range records.ExportFormats {
+		formats = append(formats, string(format))
+	}
+
+	name := mv.topics[0]
+	if len(mv.topics) > 1 {
+		name = "messages"
+	}
+
+	form := tview.NewForm()
+	form.
+		AddInputField("Path", name+".jsonl", 60, nil, nil).
+		AddDropDown("Format", formats, 0, func(option string, _ int) {
+			path := form.GetFormItemByLabel("Path").(*tview.InputField)
+			if option == string(records.FormatRaw) {
+				path.SetText(name)
+			} else {
+				path.SetText(name + "." + option)
+			}
+		}).
+		AddButton("Export", func() {
+			_, format := form.GetFormItemByLabel("Format").(*tview.DropDown).GetCurrentOption()
+			path := form.GetFormItemByLabel("Path").(*tview.InputField).GetText()
+			mv.app.RemovePage("export")
+			if err := mv.startExport(path, records.Format(format)); err != nil {
+				dialog.ShowError(mv.app, err.Error())
+			}
+		}).
+		AddButton("Cancel", func() {
+			mv.app.RemovePage("export")
+		}).
+		SetCancelFunc(func() {
+			mv.app.RemovePage("export")
+		}).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle(" Export messages (buffered matches, then new ones until stopped) ").
+		SetTitleAlign(tview.AlignLeft)
+
+	mv.app.AddPage("export", centered(form, 80, 9), true)
+}
+
+// startExport writes the buffered messages matching the filter to the file
+// and keeps streaming the new matching messages until the export is
+// stopped, so large exports go to disk rather than through the buffer
+func (mv *MessageViewer) startExport(path string, format records.Format) error {
+	writer, err := records.NewFileWriter(path, format)
+	if err != nil {
+		return fmt.Errorf("failed to start export: %w", err)
+	}
+
+	// new messages wait on exportMutex until the snapshot is written, and
+	// are exported only when buffered after the snapshot was taken
+	mv.exportMutex.Lock()
+	defer mv.exportMutex.Unlock()
+
+	mv.mutex.Lock()
+	snapshot, _, _ := mv.buffer.Since(0)
+	f := mv.filter
+	mv.exporter = writer
+	mv.exportPath = path
+	mv.exported = 0
+	mv.mutex.Unlock()
+
+	exported := 0
+	for i := range snapshot {
+		if !f.Match(&snapshot[i]) {
+			continue
+		}
+		if err := writer.Write(&snapshot[i]); err != nil {
+			mv.mutex.Lock()
+			mv.exporter = nil
+			mv.mutex.Unlock()
+			writer.Close()
+			return fmt.Errorf("failed to export: %w", err)
+		}
+		exported++
+	}
+
+	mv.mutex.Lock()
+	mv.exported = exported
+	mv.mutex.Unlock()
+
+	mv.updateTitle()
+	return nil
+}
+
+// stopExport closes the export file
+func (mv *MessageViewer) stopExport() {
+	mv.exportMutex.Lock()
+	mv.mutex.Lock()
+	writer, path, exported := mv.exporter, mv.exportPath, mv.exported
+	mv.exporter = nil
+	mv.mutex.Unlock()
+
+	if writer == nil {
+		mv.exportMutex.Unlock()
+		return
+	}
+	err := writer.Close()
+	mv.exportMutex.Unlock()
+
+	if err != nil {
+		dialog.ShowError(mv.app, fmt.Sprintf("Export to %s failed: %v", path, err))
+	} else {
+		mv.notify(fmt.Sprintf("Exported %d messages to %s", exported, path), false)
+	}
+	mv.render()
+}
+
+// export appends a matching message to the export running when it was
+// buffered, unless that export was stopped meanwhile
+func (mv *MessageViewer) export(writer records.Writer, msg *models.Message) {
+	mv.exportMutex.Lock()
+	mv.mutex.Lock()
+	if mv.exporter != writer {
+		mv.mutex.Unlock()
+		mv.exportMutex.Unlock()
+		return
+	}
+	path := mv.exportPath
+	mv.mutex.Unlock()
+
+	err := writer.Write(msg)
+	mv.mutex.Lock()
+	if err != nil {
+		mv.exporter = nil
+	} else {
+		mv.exported++
+	}
+	mv.mutex.Unlock()
+	if err != nil {
+		writer.Close()
+	}
+	mv.exportMutex.Unlock()
+
+	if err != nil {
+		mv.app.QueueUpdateDraw(func() {
+			dialog.ShowError(mv.app, fmt.Sprintf("Export to %s failed: %v", path, err))
+		})
+	}
+}
+
+// centered wraps a primitive so it is displayed in the middle of the screen
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
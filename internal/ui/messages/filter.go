@@ -0,0 +1,76 @@
+package messages
+
+import (
+	"fmt"
+
+	"github.com/clemsau/kafe/internal/filter"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// FilterBar edits the filter expression applied to incoming messages
+type FilterBar struct {
+	*tview.InputField
+	viewer *MessageViewer
+}
+
+// NewFilterBar creates a new filter bar for the message viewer
+func NewFilterBar(viewer *MessageViewer) *FilterBar {
+	bar := &FilterBar{
+		InputField: tview.NewInputField(),
+		viewer:     viewer,
+	}
+
+	bar.
+		SetLabel("/").
+		SetPlaceholder(`e.g. value.status == "FAILED" && partition == 2`).
+		SetPlaceholderTextColor(tcell.ColorGray).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle("Filter").
+		SetTitleAlign(tview.AlignLeft)
+
+	bar.InputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			if bar.apply() {
+				bar.Deactivate()
+			}
+			return nil
+		case tcell.KeyEsc:
+			bar.Deactivate()
+			return nil
+		}
+		return event
+	})
+
+	return bar
+}
+
+// apply parses the current text and hands it to the viewer. Invalid
+// expressions are reported in the bar and the previous filter is kept.
+func (f *FilterBar) apply() bool {
+	parsed, err := filter.Parse(f.GetText())
+	if err != nil {
+		f.SetTitle(fmt.Sprintf("Filter - %v", err)).
+			SetTitleColor(tcell.ColorRed)
+		return false
+	}
+
+	f.SetTitle("Filter").SetTitleColor(tcell.ColorDefault)
+	f.viewer.SetFilter(parsed)
+	return true
+}
+
+// Activate focuses the filter bar
+func (f *FilterBar) Activate() {
+	f.SetBorderColor(tcell.ColorYellow)
+	f.viewer.app.SetFocus(f)
+}
+
+// Deactivate returns focus to the messages but maintains the filter
+func (f *FilterBar) Deactivate() {
+	f.SetBorderColor(tcell.ColorDefault)
+	f.viewer.app.SetFocus(f.viewer.textView)
+}
@@ -0,0 +1,120 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/models"
+)
+
+// abortCheckInterval is how often records read uncommitted are checked for
+// aborted transactions
+const abortCheckInterval = 2 * time.Second
+
+// toggleIsolation switches between reading committed and uncommitted
+// records. Consumers restart after the last consumed messages, in the
+// background as connecting to the brokers takes a while.
+func (mv *MessageViewer) toggleIsolation() {
+	go func() {
+		mv.consumersMutex.Lock()
+		if mv.stopped {
+			mv.consumersMutex.Unlock()
+			return
+		}
+		mv.stopConsumers()
+
+		mv.mutex.Lock()
+		mv.readCommitted = !mv.readCommitted
+		readCommitted := mv.readCommitted
+		mv.mutex.Unlock()
+
+		err := mv.startConsumers()
+		mv.consumersMutex.Unlock()
+
+		if err != nil {
+			mv.notify(err.Error(), true)
+		} else if readCommitted {
+			mv.notify("Reading committed records only, aborted transactions are hidden", false)
+		} else {
+			mv.notify("Reading uncommitted records, aborted transactions are marked once decided", false)
+		}
+		mv.app.QueueUpdateDraw(mv.render)
+	}()
+}
+
+// abortLoop marks the buffered records of aborted transactions while
+// reading uncommitted. The brokers only report aborted transactions to
+// read committed fetches, so the consumed offsets are scanned again once
+// the last stable offset passed them. Only the partitions where consumed
+// offsets skipped a transaction marker are scanned.
+func (mv *MessageViewer) abortLoop(ctx context.Context) {
+	ticker := time.NewTicker(abortCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mv.checkAborted()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (mv *MessageViewer) checkAborted() {
+	mv.mutex.Lock()
+	if mv.readCommitted {
+		mv.mutex.Unlock()
+		return
+	}
+	type scan struct{ from, to int64 }
+	scans := make(map[topicPartition]scan)
+	for tp, last := range mv.positions {
+		if !mv.transactional[tp] {
+			continue
+		}
+		if from := mv.abortChecked[tp]; from <= last {
+			scans[tp] = scan{from: from, to: last + 1}
+		}
+	}
+	mv.mutex.Unlock()
+
+	for tp, s := range scans {
+		aborted, checked, err := mv.client.AbortedRanges(tp.topic, tp.partition, s.from, s.to)
+		if err != nil {
+			mv.notify(fmt.Sprintf("%s partition %d, failed to check aborted transactions: %v", tp.topic, tp.partition, err), true)
+		}
+
+		mv.mutex.Lock()
+		if checked > mv.abortChecked[tp] {
+			mv.abortChecked[tp] = checked
+		}
+		mv.mutex.Unlock()
+
+		if len(aborted) > 0 {
+			mv.markAborted(tp, aborted)
+
+			mv.mutex.Lock()
+			mv.dirty = true
+			mv.rebuild = true
+			mv.mutex.Unlock()
+		}
+	}
+}
+
+// markAborted flags the buffered records in the aborted offset ranges
+func (mv *MessageViewer) markAborted(tp topicPartition, aborted []kafka.OffsetRange) {
+	mv.buffer.Update(func(msg *models.Message) {
+		if msg.Topic != tp.topic || msg.Partition != tp.partition {
+			return
+		}
+		for _, r := range aborted {
+			if r.Contains(msg.Offset) {
+				msg.Aborted = true
+				return
+			}
+		}
+	})
+}
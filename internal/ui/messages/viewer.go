@@ -4,31 +4,127 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/clemsau/kafe/internal/deserialize"
+	"github.com/clemsau/kafe/internal/filter"
 	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/records"
 	"github.com/clemsau/kafe/internal/ui"
 	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/dialog"
+	"github.com/clemsau/kafe/internal/ui/produce"
 	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/clemsau/kafe/internal/ui/utils"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
+const (
+	// renderInterval is how often new messages are drawn, so a busy topic
+	// costs one redraw per interval instead of one per message
+	renderInterval = 250 * time.Millisecond
+	// maxNotices is how many notices are kept above the messages
+	maxNotices = 5
+	// maxPickerMessages is how many messages the duplicate picker lists
+	maxPickerMessages = 100
+	// maxTopicPartitions is how many partitions of each topic are consumed
+	maxTopicPartitions = 5
+)
+
+// topicColors tag the messages of each topic in a merged view
+var topicColors = []string{"aqua", "fuchsia", "orange", "lime", "yellow", "skyblue", "violet", "gold"}
+
+// topicPartition identifies a consumed partition
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// BufferOptions bounds the messages kept by a MessageViewer
+type BufferOptions struct {
+	MaxMessages int
+	// MaxBytes caps the size of the buffered keys, values and headers, 0 for no cap
+	MaxBytes int
+}
+
+// DefaultBuffer is the buffer used by new message viewers
+var DefaultBuffer = BufferOptions{
+	MaxMessages: 1000,
+	MaxBytes:    16 * 1024 * 1024,
+}
+
 type MessageViewer struct {
 	*tview.Flex
 	textView  *tview.TextView
+	filterBar *FilterBar
 	topBar    *topbar.TopBar
 	app       *ui.App
 	client    *kafka.Client
-	topic     string
+	topics    []string
 	consumers []sarama.PartitionConsumer
 	cancel    context.CancelFunc
 	mutex     sync.Mutex
+	buffer    *models.MessageBuffer
+	filter    *filter.Filter
+	notices   []string
+	paused    bool
+	dirty     bool
+	pending   int
+	// displayed holds the messages in the text view, after header bytes of
+	// notices. rendered counts the buffered messages already rendered, and
+	// rebuild forces the next render to rewrite the whole text instead of
+	// appending the new messages.
+	displayed []displayedMessage
+	header    int
+	rendered  int64
+	rebuild   bool
+
+	// partitions holds the consumed partitions, a sample of the
+	// totalPartitions of the topics when they have many
+	partitions      []topicPartition
+	totalPartitions int
+	consumer        sarama.Consumer
+	consumeCancel   context.CancelFunc
+	readCommitted   bool
+	// consumersMutex serializes starting and stopping the consumers, which
+	// stopped prevents once the viewer is closed
+	consumersMutex sync.Mutex
+	stopped        bool
+	// positions holds the last consumed offset of each partition, and
+	// abortChecked the offset the next scan for aborted records starts from.
+	// transactional holds the partitions whose consumed offsets skipped
+	// one, as they do over transaction markers.
+	positions     map[topicPartition]int64
+	abortChecked  map[topicPartition]int64
+	transactional map[topicPartition]bool
+
+	deserializers      *deserialize.Deserializers
+	topicDeserializers map[string]topicDeserializers
+
+	exporter   records.Writer
+	exportPath string
+	exported   int
+	// exportMutex serializes the writes to the exporter, it is locked before mutex
+	exportMutex sync.Mutex
 }
 
-func NewMessageViewer(app *ui.App, client *kafka.Client, topic string) *MessageViewer {
+// displayedMessage is a message in the text view, with its position in the
+// buffer and the length of its text
+type displayedMessage struct {
+	models.Message
+	position int64
+	length   int
+}
+
+// NewMessageViewer creates a live view of the topics. The messages of
+// several topics are merged and ordered by timestamp.
+func NewMessageViewer(app *ui.App, client *kafka.Client, topics []string) *MessageViewer {
 	mv := &MessageViewer{
 		textView: tview.NewTextView().
 			SetDynamicColors(true).
@@ -36,11 +132,33 @@ func NewMessageViewer(app *ui.App, client *kafka.Client, topic string) *MessageV
 			SetWrap(true),
 		app:    app,
 		client: client,
-		topic:  topic,
+		topics: topics,
+		buffer: models.NewMessageBuffer(DefaultBuffer.MaxMessages, DefaultBuffer.MaxBytes),
+
+		rebuild: true,
+
+		positions:     make(map[topicPartition]int64),
+		abortChecked:  make(map[topicPartition]int64),
+		transactional: make(map[topicPartition]bool),
 	}
 
+	mv.deserializers = deserializersOf(client)
+	mv.topicDeserializers = make(map[string]topicDeserializers)
+	for _, topic := range topics {
+		key, value := mv.deserializers.ForTopic(topic)
+		mv.topicDeserializers[topic] = topicDeserializers{key: key, value: value}
+	}
+
+	mv.filterBar = NewFilterBar(mv)
+
 	mv.topBar = topbar.NewTopBar([]controls.Control{
 		{Key: "Esc", Description: "back to topics"},
+		{Key: "/", Description: "filter"},
+		{Key: "p", Description: "pause/resume"},
+		{Key: "d", Description: "duplicate a message"},
+		{Key: "x", Description: "start/stop export"},
+		{Key: "c", Description: "change decoders"},
+		{Key: "i", Description: "toggle read committed"},
 		{Key: "q", Description: "quit"},
 	})
 
@@ -48,6 +166,7 @@ func NewMessageViewer(app *ui.App, client *kafka.Client, topic string) *MessageV
 		SetDirection(tview.FlexRow).
 		AddItem(mv.topBar, mv.topBar.GetHeight(), 0, false).
 		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(mv.filterBar, 3, 0, false).
 		AddItem(mv.textView, 0, 1, true)
 
 	mv.setupUI()
@@ -55,93 +174,488 @@ func NewMessageViewer(app *ui.App, client *kafka.Client, topic string) *MessageV
 }
 
 func (mv *MessageViewer) setupUI() {
-	mv.textView.SetBorder(true).
-		SetTitle(fmt.Sprintf(" Messages - %s ", mv.topic))
+	mv.textView.SetBorder(true)
+	mv.updateTitle()
 
 	mv.SetInputCapture(mv.handleInput)
 }
 
 func (mv *MessageViewer) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	if mv.filterBar.HasFocus() {
+		return event
+	}
+
 	switch event.Key() {
 	case tcell.KeyEscape:
 		mv.Stop()
 		mv.app.RemovePage("messages")
 		return nil
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case '/':
+			mv.filterBar.Activate()
+			return nil
+		case 'p':
+			mv.togglePause()
+			return nil
+		case 'd':
+			mv.showDuplicatePicker()
+			return nil
+		case 'x':
+			mv.toggleExport()
+			return nil
+		case 'c':
+			mv.showDeserializerForm()
+			return nil
+		case 'i':
+			mv.toggleIsolation()
+			return nil
+		}
 	}
 	return event
 }
 
-func (mv *MessageViewer) Start() error {
-	partitions, err := mv.client.Partitions(mv.topic)
-	if err != nil {
-		return fmt.Errorf("failed to get partitions: %w", err)
+// SetFilter replaces the active filter. Consumers keep running, and the
+// buffered messages are filtered again with the new expression.
+func (mv *MessageViewer) SetFilter(f *filter.Filter) {
+	mv.mutex.Lock()
+	mv.filter = f
+	mv.rebuild = true
+	mv.mutex.Unlock()
+
+	mv.render()
+}
+
+// togglePause freezes or resumes the display. Messages keep being consumed
+// into the buffer while paused.
+func (mv *MessageViewer) togglePause() {
+	mv.mutex.Lock()
+	mv.paused = !mv.paused
+	mv.rebuild = true
+	mv.mutex.Unlock()
+
+	mv.render()
+}
+
+// updateTitle shows the topic, how many messages are displayed and buffered
+// and whether the display is paused or exporting
+func (mv *MessageViewer) updateTitle() {
+	count, bytes, dropped := mv.buffer.Stats()
+
+	mv.mutex.Lock()
+	defer mv.mutex.Unlock()
+
+	parts := []string{"Messages", strings.Join(mv.topics, ", ")}
+	if len(mv.partitions) < mv.totalPartitions {
+		parts = append(parts, fmt.Sprintf("sampling %d of %d partitions", len(mv.partitions), mv.totalPartitions))
+	}
+	if !mv.filter.IsEmpty() {
+		parts = append(parts, fmt.Sprintf("%d matched", len(mv.displayed)))
+	}
+	buffered := fmt.Sprintf("%d buffered (%s)", count, utils.FormatBytes(bytes))
+	if dropped > 0 {
+		buffered += fmt.Sprintf(", %d dropped", dropped)
+	}
+	parts = append(parts, buffered)
+	if mv.readCommitted {
+		parts = append(parts, "read committed")
+	} else {
+		parts = append(parts, "read uncommitted")
 	}
+	parts = append(parts, mv.deserializersTitle()...)
+	if mv.paused {
+		parts = append(parts, fmt.Sprintf("PAUSED, %d new", mv.pending))
+	}
+	if mv.exporter != nil {
+		parts = append(parts, fmt.Sprintf("exporting %d to %s", mv.exported, tview.Escape(mv.exportPath)))
+	}
+	title := " " + strings.Join(parts, " - ") + " "
+	mv.textView.SetTitle(title)
+}
 
-	numPartitions := len(partitions)
-	if numPartitions > 5 {
-		rand.Shuffle(len(partitions), func(i, j int) {
-			partitions[i], partitions[j] = partitions[j], partitions[i]
-		})
-		partitions = partitions[:5]
+// topicColor returns the colour tagging the messages of a topic
+func (mv *MessageViewer) topicColor(topic string) string {
+	for i, t := range mv.topics {
+		if t == topic {
+			return topicColors[i%len(topicColors)]
+		}
+	}
+	return "white"
+}
+
+func (mv *MessageViewer) Start() error {
+	var sampled []topicPartition
+	total := 0
+	for _, topic := range mv.topics {
+		partitions, err := mv.client.Partitions(topic)
+		if err != nil {
+			return fmt.Errorf("failed to get partitions of %s: %w", topic, err)
+		}
+
+		total += len(partitions)
+		if len(partitions) > maxTopicPartitions {
+			rand.Shuffle(len(partitions), func(i, j int) {
+				partitions[i], partitions[j] = partitions[j], partitions[i]
+			})
+			partitions = partitions[:maxTopicPartitions]
+		}
+		for _, partition := range partitions {
+			sampled = append(sampled, topicPartition{topic: topic, partition: partition})
+		}
 	}
+	mv.mutex.Lock()
+	mv.partitions = sampled
+	mv.totalPartitions = total
+	mv.mutex.Unlock()
+	mv.updateTitle()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	mv.cancel = cancel
 
+	if err := mv.startConsumers(); err != nil {
+		cancel()
+		return err
+	}
+
+	go mv.renderLoop(ctx)
+	go mv.abortLoop(ctx)
+	return nil
+}
+
+// startConsumers consumes the partitions with the current isolation level,
+// from the newest offsets or after the last consumed messages
+func (mv *MessageViewer) startConsumers() error {
 	config := sarama.NewConfig()
+	config.Version = mv.client.Config().Version
+	if mv.readCommitted {
+		config.Consumer.IsolationLevel = sarama.ReadCommitted
+	}
+
 	consumer, err := sarama.NewConsumer(mv.client.GetAddresses(), config)
 	if err != nil {
 		return fmt.Errorf("failed to create consumer: %w", err)
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	mv.consumer = consumer
+	mv.consumeCancel = cancel
+
+	for _, tp := range mv.partitions {
+		offset := sarama.OffsetNewest
+		mv.mutex.Lock()
+		if last, ok := mv.positions[tp]; ok {
+			offset = last + 1
+		}
+		mv.mutex.Unlock()
 
-	for _, partition := range partitions {
-		partitionConsumer, err := consumer.ConsumePartition(mv.topic, partition, sarama.OffsetNewest)
+		partitionConsumer, err := consumer.ConsumePartition(tp.topic, tp.partition, offset)
 		if err != nil {
-			mv.writeMessage(fmt.Sprintf("Failed to consume partition %d: %v", partition, err), partition, true)
+			mv.notify(fmt.Sprintf("Failed to consume %s partition %d: %v", tp.topic, tp.partition, err), true)
 			continue
 		}
 		mv.consumers = append(mv.consumers, partitionConsumer)
 
-		go func(pc sarama.PartitionConsumer, partition int32) {
+		go func(pc sarama.PartitionConsumer, tp topicPartition) {
 			for {
 				select {
-				case msg := <-pc.Messages():
-					mv.writeMessage(string(msg.Value), partition, false)
-				case err := <-pc.Errors():
-					mv.writeMessage(fmt.Sprintf("Partition %d, error: %v", partition, err), partition, true)
+				case msg, ok := <-pc.Messages():
+					if !ok {
+						return
+					}
+					message := kafka.NewMessage(msg)
+					mv.decode(&message)
+					mv.receive(message)
+				case err, ok := <-pc.Errors():
+					if !ok {
+						return
+					}
+					mv.notify(fmt.Sprintf("%s partition %d, error: %v", tp.topic, tp.partition, err), true)
 				case <-ctx.Done():
 					return
 				}
 			}
-		}(partitionConsumer, partition)
+		}(partitionConsumer, tp)
 	}
-
 	return nil
 }
 
+// stopConsumers closes the partition consumers
+func (mv *MessageViewer) stopConsumers() {
+	if mv.consumeCancel != nil {
+		mv.consumeCancel()
+	}
+	for _, consumer := range mv.consumers {
+		consumer.Close()
+	}
+	mv.consumers = nil
+	if mv.consumer != nil {
+		mv.consumer.Close()
+		mv.consumer = nil
+	}
+}
+
 func (mv *MessageViewer) Stop() {
+	mv.stopExport()
 	if mv.cancel != nil {
 		mv.cancel()
 	}
-	for _, consumer := range mv.consumers {
-		consumer.Close()
+
+	mv.consumersMutex.Lock()
+	defer mv.consumersMutex.Unlock()
+	mv.stopped = true
+	mv.stopConsumers()
+}
+
+// receive buffers a consumed message and streams it to the running export
+// when it matches the filter. The message is buffered under the mutex, so
+// an export starting meanwhile has it either in its snapshot or as new.
+func (mv *MessageViewer) receive(msg models.Message) {
+	mv.mutex.Lock()
+	mv.buffer.Add(msg)
+	tp := topicPartition{topic: msg.Topic, partition: msg.Partition}
+	if last, ok := mv.positions[tp]; ok && msg.Offset > last+1 {
+		mv.transactional[tp] = true
+	}
+	mv.positions[tp] = msg.Offset
+	if _, ok := mv.abortChecked[tp]; !ok {
+		mv.abortChecked[tp] = msg.Offset
+	}
+	mv.dirty = true
+	if mv.paused {
+		mv.pending++
+	}
+	var exporter records.Writer
+	if mv.exporter != nil && mv.filter.Match(&msg) {
+		exporter = mv.exporter
+	}
+	mv.mutex.Unlock()
+
+	if exporter != nil {
+		mv.export(exporter, &msg)
 	}
 }
 
-func (mv *MessageViewer) writeMessage(msg string, partition int32, err bool) {
+// notify records a notice, such as a consumer error, displayed above the messages
+func (mv *MessageViewer) notify(notice string, err bool) {
 	mv.mutex.Lock()
 	defer mv.mutex.Unlock()
 
-	color := "green"
+	color := "yellow"
 	if err {
 		color = "red"
 	}
+	mv.notices = append(mv.notices, fmt.Sprintf("[%s]%s %s[-]", color, time.Now().Format("15:04:05"), tview.Escape(notice)))
+	if len(mv.notices) > maxNotices {
+		mv.notices = mv.notices[len(mv.notices)-maxNotices:]
+	}
+	mv.dirty = true
+	mv.rebuild = true
+}
+
+// renderLoop redraws the messages when new ones arrived, unless paused
+func (mv *MessageViewer) renderLoop(ctx context.Context) {
+	ticker := time.NewTicker(renderInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mv.mutex.Lock()
+			dirty := mv.dirty
+			mv.mutex.Unlock()
+
+			if dirty {
+				mv.app.QueueUpdateDraw(func() {
+					mv.mutex.Lock()
+					paused := mv.paused
+					mv.mutex.Unlock()
+
+					if paused {
+						mv.updateTitle()
+						return
+					}
+					mv.render()
+				})
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// render draws the buffered messages matching the filter. New messages are
+// appended to the text view and the messages evicted from the buffer are
+// cut from its head, the whole text is only rewritten when the filter or
+// the notices changed, or when a merged view receives messages older than
+// the displayed ones. While paused only the title is refreshed, which
+// keeps the display frozen for scrolling back.
+func (mv *MessageViewer) render() {
+	mv.mutex.Lock()
+	if mv.paused {
+		mv.mutex.Unlock()
+		mv.updateTitle()
+		return
+	}
+	f := mv.filter
+	rebuild := mv.rebuild
+	from := mv.rendered
+	notices := append([]string(nil), mv.notices...)
+	mv.dirty = false
+	mv.rebuild = false
+	mv.pending = 0
+	mv.mutex.Unlock()
+
+	if rebuild {
+		from = 0
+	}
+	messages, dropped, added := mv.buffer.Since(from)
+	position := added - int64(len(messages))
+
+	var text strings.Builder
+	if rebuild {
+		for _, notice := range notices {
+			fmt.Fprintln(&text, notice)
+		}
+		if !f.IsEmpty() {
+			fmt.Fprintf(&text, "[yellow]Filter: %s[-]\n", tview.Escape(f.String()))
+		}
+	}
+	header := text.Len()
+
+	var matched []displayedMessage
+	for i, msg := range messages {
+		if f.Match(&msg) {
+			matched = append(matched, displayedMessage{Message: msg, position: position + int64(i)})
+		}
+	}
+	merged := len(mv.topics) > 1
+	if merged {
+		sort.SliceStable(matched, func(i, j int) bool {
+			return matched[i].Timestamp.Before(matched[j].Timestamp)
+		})
+	}
+
+	mv.mutex.Lock()
+	evicted, cut := 0, 0
+	if !rebuild {
+		for evicted < len(mv.displayed) && mv.displayed[evicted].position < dropped {
+			cut += mv.displayed[evicted].length
+			evicted++
+		}
+		if merged && !inOrder(mv.displayed[evicted:], matched, dropped) {
+			mv.rebuild = true
+			mv.mutex.Unlock()
+			mv.render()
+			return
+		}
+	}
+	mv.mutex.Unlock()
+
+	for i := range matched {
+		msg := &matched[i]
+		tag := ""
+		if merged {
+			tag = fmt.Sprintf("[%s]%s[-] ", mv.topicColor(msg.Topic), tview.Escape(msg.Topic))
+		}
+		if msg.Aborted {
+			tag += "[red]ABORTED[-] "
+		}
+		line := fmt.Sprintf("[green]%s [partition-%d][-] %s%s\n",
+			msg.Timestamp.Format("15:04:05"),
+			msg.Partition,
+			tag,
+			tview.Escape(msg.ValueText()))
+		if msg.DecodeErr != nil {
+			line += fmt.Sprintf("[red]  decode error: %s[-]\n", tview.Escape(msg.DecodeErr.Error()))
+		}
+		text.WriteString(line)
+		msg.length = len(line)
+	}
+
+	mv.mutex.Lock()
+	if rebuild {
+		mv.displayed = matched
+		mv.header = header
+	} else {
+		mv.displayed = append(mv.displayed[evicted:], matched...)
+	}
+	header = mv.header
+	mv.rendered = added
+	mv.mutex.Unlock()
+
+	switch {
+	case rebuild:
+		mv.textView.SetText(text.String())
+	case cut > 0:
+		current := mv.textView.GetText(false)
+		mv.textView.SetText(current[:header] + current[header+cut:] + text.String())
+	case text.Len() > 0:
+		fmt.Fprint(mv.textView, text.String())
+	}
+	mv.textView.ScrollToEnd()
+	mv.updateTitle()
+}
+
+// inOrder tells whether the matched messages of a merged view can be
+// appended to the kept ones: they must not be older than the last kept
+// message, and no kept message may have been evicted from the buffer.
+func inOrder(kept, matched []displayedMessage, dropped int64) bool {
+	for i := range kept {
+		if kept[i].position < dropped {
+			return false
+		}
+	}
+	return len(kept) == 0 || len(matched) == 0 || !matched[0].Timestamp.Before(kept[len(kept)-1].Timestamp)
+}
+
+// displayedMessages returns the messages currently displayed, oldest first
+func (mv *MessageViewer) displayedMessages() []models.Message {
+	mv.mutex.Lock()
+	defer mv.mutex.Unlock()
+
+	messages := make([]models.Message, len(mv.displayed))
+	for i := range mv.displayed {
+		messages[i] = mv.displayed[i].Message
+	}
+	return messages
+}
+
+// showDuplicatePicker lists the displayed messages and opens the produce
+// dialog pre-filled with the chosen one
+func (mv *MessageViewer) showDuplicatePicker() {
+	displayed := mv.displayedMessages()
+	if len(displayed) == 0 {
+		dialog.ShowError(mv.app, "No message to duplicate yet")
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(true)
+	list.SetBorder(true).
+		SetTitle(" Duplicate message (Enter to select, Esc to cancel) ").
+		SetTitleAlign(tview.AlignLeft)
+
+	for i := len(displayed) - 1; i >= 0 && i >= len(displayed)-maxPickerMessages; i-- {
+		msg := displayed[i]
+		list.AddItem(
+			tview.Escape(truncate(msg.ValueText(), 120)),
+			tview.Escape(fmt.Sprintf("partition %d, offset %d, key %s", msg.Partition, msg.Offset, truncate(msg.KeyText(), 60))),
+			0,
+			func() {
+				mv.app.RemovePage("duplicate")
+				mv.app.AddPage("produce", produce.NewProduceDialog(mv.app, mv.client, msg.Topic, &msg), true)
+			})
+	}
 
-	mv.app.QueueUpdateDraw(func() {
-		fmt.Fprintf(mv.textView, "[%s]%s [partition-%d][-] %s\n",
-			color,
-			time.Now().Format("15:04:05"),
-			partition,
-			msg)
+	list.SetDoneFunc(func() {
+		mv.app.RemovePage("duplicate")
 	})
+
+	mv.app.AddPage("duplicate", list, true)
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length]) + "…"
 }
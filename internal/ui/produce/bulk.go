@@ -0,0 +1,199 @@
+package produce
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/records"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// BulkDialog produces every record of a JSON lines or CSV file to a topic
+type BulkDialog struct {
+	*tview.Flex
+	form   *tview.Form
+	status *tview.TextView
+	topBar *topbar.TopBar
+	app    *ui.App
+	client *kafka.Client
+	topic  string
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+}
+
+// NewBulkDialog creates a bulk produce page for the topic
+func NewBulkDialog(app *ui.App, client *kafka.Client, topic string) *BulkDialog {
+	bd := &BulkDialog{
+		form: tview.NewForm(),
+		status: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true).
+			SetWrap(true),
+		app:    app,
+		client: client,
+		topic:  topic,
+	}
+
+	bd.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Tab", Description: "next field"},
+		{Key: "Esc", Description: "close"},
+	})
+
+	bd.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(bd.topBar, bd.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(bd.form, 19, 0, true).
+		AddItem(bd.status, 0, 1, false)
+
+	bd.setupUI()
+	return bd
+}
+
+func (bd *BulkDialog) setupUI() {
+	bd.form.
+		AddInputField("File", "", 0, nil, nil).
+		AddDropDown("Format", []string{"from extension", string(records.FormatJSONL), string(records.FormatCSV)}, 0, nil).
+		AddInputField("Rate (msg/s)", "0", 10, tview.InputFieldFloat, nil).
+		AddDropDown("Partitioner", kafka.Partitioners, 0, nil).
+		AddDropDown("Compression", kafka.Compressions, 0, nil).
+		AddCheckbox("Idempotent", false, nil).
+		AddInputField("Transactional ID", "", 40, nil, nil).
+		AddButton("Start", bd.start).
+		AddButton("Cancel", bd.Stop).
+		AddButton("Close", bd.close).
+		SetCancelFunc(bd.close).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle(fmt.Sprintf(" Bulk produce - %s ", bd.topic)).
+		SetTitleAlign(tview.AlignLeft)
+
+	bd.status.SetBorder(true).
+		SetTitle(" Result ").
+		SetTitleAlign(tview.AlignLeft)
+	fmt.Fprint(bd.status, `[gray]Each JSON line is an object such as {"key": "k", "value": {...}, "headers": {"h": "v"}, "partition": 0}.
+CSV files start with a header row naming the key, value, headers and partition columns.[-]`+"\n")
+}
+
+// start reads the form and produces the file in the background
+func (bd *BulkDialog) start() {
+	path := bd.form.GetFormItemByLabel("File").(*tview.InputField).GetText()
+	formatIndex, formatName := bd.form.GetFormItemByLabel("Format").(*tview.DropDown).GetCurrentOption()
+	if formatIndex == 0 {
+		formatName = ""
+	}
+	format, err := records.ParseFormat(formatName, path)
+	if err != nil {
+		bd.report(err)
+		return
+	}
+
+	rate, err := strconv.ParseFloat(bd.form.GetFormItemByLabel("Rate (msg/s)").(*tview.InputField).GetText(), 64)
+	if err != nil || rate < 0 {
+		bd.report(fmt.Errorf("invalid rate"))
+		return
+	}
+
+	_, partitioner := bd.form.GetFormItemByLabel("Partitioner").(*tview.DropDown).GetCurrentOption()
+	_, compression := bd.form.GetFormItemByLabel("Compression").(*tview.DropDown).GetCurrentOption()
+	opts := kafka.BulkOptions{
+		ProducerOptions: kafka.ProducerOptions{
+			Partitioner:     partitioner,
+			Compression:     compression,
+			Idempotent:      bd.form.GetFormItemByLabel("Idempotent").(*tview.Checkbox).IsChecked(),
+			TransactionalID: bd.form.GetFormItemByLabel("Transactional ID").(*tview.InputField).GetText(),
+		},
+		Rate: rate,
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		bd.report(err)
+		return
+	}
+	reader, err := records.NewReader(f, format)
+	if err != nil {
+		f.Close()
+		bd.report(err)
+		return
+	}
+
+	bd.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	bd.mutex.Lock()
+	bd.cancel = cancel
+	bd.mutex.Unlock()
+
+	bd.status.Clear()
+	fmt.Fprint(bd.status, "[yellow]Producing...[-]\n")
+
+	go func() {
+		defer f.Close()
+		result, err := bd.client.ProduceBulk(ctx, bd.topic, reader.Next, opts, func(progress kafka.BulkResult) {
+			delivered, failed := progress.Delivered, progress.Failed
+			bd.app.QueueUpdateDraw(func() {
+				bd.status.SetTitle(fmt.Sprintf(" Result - delivered %d, failed %d ", delivered, failed))
+			})
+		})
+		bd.app.QueueUpdateDraw(func() {
+			bd.showSummary(result, err)
+		})
+	}()
+}
+
+// Stop cancels the running bulk produce, if any
+func (bd *BulkDialog) Stop() {
+	bd.mutex.Lock()
+	defer bd.mutex.Unlock()
+	if bd.cancel != nil {
+		bd.cancel()
+		bd.cancel = nil
+	}
+}
+
+func (bd *BulkDialog) showSummary(result kafka.BulkResult, err error) {
+	bd.status.Clear()
+	bd.status.SetTitle(" Result ")
+
+	if err != nil {
+		fmt.Fprintf(bd.status, "[red]Stopped: %s[-]\n", tview.Escape(err.Error()))
+	}
+	fmt.Fprintf(bd.status, "[green]Delivered: %d[-]\n", result.Delivered)
+	if result.Failed == 0 {
+		fmt.Fprintf(bd.status, "Failed: 0\nElapsed: %s\n", result.Elapsed.Round(time.Millisecond))
+		return
+	}
+
+	fmt.Fprintf(bd.status, "[red]Failed: %d[-]\nElapsed: %s\n\n[yellow]Failure reasons:[-]\n", result.Failed, result.Elapsed.Round(time.Millisecond))
+	for _, reason := range result.SortedReasons() {
+		fmt.Fprintf(bd.status, "  %6d  %s\n", result.Reasons[reason], tview.Escape(reason))
+	}
+
+	fmt.Fprint(bd.status, "\n[yellow]Failed records:[-]\n")
+	for _, failure := range result.Failures {
+		fmt.Fprintf(bd.status, "  line %d: %s\n", failure.Line, tview.Escape(failure.Err.Error()))
+	}
+	if result.Failed > len(result.Failures) {
+		fmt.Fprintf(bd.status, "  ... and %d more\n", result.Failed-len(result.Failures))
+	}
+}
+
+func (bd *BulkDialog) report(err error) {
+	bd.status.Clear()
+	fmt.Fprintf(bd.status, "[red]%s[-]\n", tview.Escape(err.Error()))
+}
+
+func (bd *BulkDialog) close() {
+	bd.Stop()
+	bd.app.RemovePage("bulk-produce")
+}
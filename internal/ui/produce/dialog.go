@@ -0,0 +1,189 @@
+package produce
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ProduceDialog lets the user write a single message to a topic
+type ProduceDialog struct {
+	*tview.Flex
+	form   *tview.Form
+	status *tview.TextView
+	topBar *topbar.TopBar
+	app    *ui.App
+	client *kafka.Client
+	topic  string
+}
+
+// NewProduceDialog creates a produce page for the topic. When template is
+// not nil its key, value and headers pre-fill the form.
+func NewProduceDialog(app *ui.App, client *kafka.Client, topic string, template *models.Message) *ProduceDialog {
+	pd := &ProduceDialog{
+		form: tview.NewForm(),
+		status: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true).
+			SetWrap(true),
+		app:    app,
+		client: client,
+		topic:  topic,
+	}
+
+	pd.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Tab", Description: "next field"},
+		{Key: "Esc", Description: "close"},
+	})
+
+	pd.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(pd.topBar, pd.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(pd.form, 0, 1, true).
+		AddItem(pd.status, 5, 0, false)
+
+	pd.setupUI(template)
+	return pd
+}
+
+func (pd *ProduceDialog) setupUI(template *models.Message) {
+	var key, value, headers string
+	if template != nil {
+		key = string(template.Key)
+		value = string(template.Value)
+		headers = FormatHeaders(template.Headers)
+	}
+
+	pd.form.
+		AddInputField("Key", key, 0, nil, nil).
+		AddTextArea("Value", value, 0, 10, 0, nil).
+		AddTextArea("Headers", headers, 0, 3, 0, nil).
+		AddInputField("Partition", "", 10, tview.InputFieldInteger, nil).
+		AddDropDown("Partitioner", kafka.Partitioners, 0, nil).
+		AddDropDown("Compression", kafka.Compressions, 0, nil).
+		AddButton("Send", pd.send).
+		AddButton("Close", pd.close).
+		SetCancelFunc(pd.close).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle(fmt.Sprintf(" Produce - %s ", pd.topic)).
+		SetTitleAlign(tview.AlignLeft)
+
+	pd.status.SetBorder(true).
+		SetTitle(" Result ").
+		SetTitleAlign(tview.AlignLeft)
+	fmt.Fprint(pd.status, "[gray]Headers are written one per line as key: value. Leave the partition empty to use the partitioner.[-]\n")
+}
+
+// send produces the message described by the form and reports where it landed
+func (pd *ProduceDialog) send() {
+	msg, partition, err := pd.message()
+	if err != nil {
+		pd.report(err)
+		return
+	}
+
+	_, partitioner := pd.form.GetFormItemByLabel("Partitioner").(*tview.DropDown).GetCurrentOption()
+	_, compression := pd.form.GetFormItemByLabel("Compression").(*tview.DropDown).GetCurrentOption()
+
+	pd.status.Clear()
+	fmt.Fprint(pd.status, "[yellow]Sending...[-]\n")
+
+	go func() {
+		producer, err := pd.client.NewProducer(kafka.ProducerOptions{
+			Partitioner: partitioner,
+			Compression: compression,
+		})
+		if err != nil {
+			pd.app.QueueUpdateDraw(func() { pd.report(err) })
+			return
+		}
+		defer producer.Close()
+
+		partition, offset, err := producer.Send(msg, partition)
+		pd.app.QueueUpdateDraw(func() {
+			if err != nil {
+				pd.report(err)
+				return
+			}
+			pd.status.Clear()
+			fmt.Fprintf(pd.status, "[green]Delivered to partition %d at offset %d[-]\n", partition, offset)
+		})
+	}()
+}
+
+// message builds the message and target partition from the form
+func (pd *ProduceDialog) message() (models.Message, int32, error) {
+	msg := models.Message{
+		Topic: pd.topic,
+		Value: []byte(pd.form.GetFormItemByLabel("Value").(*tview.TextArea).GetText()),
+	}
+
+	if key := pd.form.GetFormItemByLabel("Key").(*tview.InputField).GetText(); key != "" {
+		msg.Key = []byte(key)
+	}
+
+	headers, err := ParseHeaders(pd.form.GetFormItemByLabel("Headers").(*tview.TextArea).GetText())
+	if err != nil {
+		return msg, 0, err
+	}
+	msg.Headers = headers
+
+	partition := kafka.AnyPartition
+	if text := pd.form.GetFormItemByLabel("Partition").(*tview.InputField).GetText(); text != "" {
+		n, err := strconv.ParseInt(text, 10, 32)
+		if err != nil || n < 0 {
+			return msg, 0, fmt.Errorf("invalid partition %q", text)
+		}
+		partition = int32(n)
+	}
+
+	return msg, partition, nil
+}
+
+func (pd *ProduceDialog) report(err error) {
+	pd.status.Clear()
+	fmt.Fprintf(pd.status, "[red]%s[-]\n", tview.Escape(err.Error()))
+}
+
+func (pd *ProduceDialog) close() {
+	pd.app.RemovePage("produce")
+}
+
+// ParseHeaders parses one `key: value` header per line
+func ParseHeaders(text string) ([]models.MessageHeader, error) {
+	var headers []models.MessageHeader
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header on line %d, expected key: value", i+1)
+		}
+		headers = append(headers, models.MessageHeader{
+			Key:   strings.TrimSpace(key),
+			Value: []byte(strings.TrimSpace(value)),
+		})
+	}
+	return headers, nil
+}
+
+// FormatHeaders writes headers in the format read by ParseHeaders
+func FormatHeaders(headers []models.MessageHeader) string {
+	lines := make([]string, 0, len(headers))
+	for _, header := range headers {
+		lines = append(lines, fmt.Sprintf("%s: %s", header.Key, header.Value))
+	}
+	return strings.Join(lines, "\n")
+}
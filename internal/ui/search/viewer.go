@@ -0,0 +1,306 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/filter"
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// maxDisplayedMatches caps the matches written to the results view
+const maxDisplayedMatches = 1000
+
+// SearchViewer runs bounded searches over a topic's history
+type SearchViewer struct {
+	*tview.Flex
+	form     *tview.Form
+	progress *tview.Table
+	results  *tview.TextView
+	topBar   *topbar.TopBar
+	app      *ui.App
+	client   *kafka.Client
+	topic    string
+	search   *kafka.Search
+	cancel   context.CancelFunc
+	shown    int
+	mutex    sync.Mutex
+}
+
+// NewSearchViewer creates a search page for the topic
+func NewSearchViewer(app *ui.App, client *kafka.Client, topic string) *SearchViewer {
+	sv := &SearchViewer{
+		form:     tview.NewForm(),
+		progress: tview.NewTable(),
+		results: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true).
+			SetWrap(true),
+		app:    app,
+		client: client,
+		topic:  topic,
+	}
+
+	sv.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Esc", Description: "back to topics"},
+		{Key: "e", Description: "edit search"},
+		{Key: "c", Description: "cancel search"},
+		{Key: "q", Description: "quit"},
+	})
+
+	sv.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(sv.topBar, sv.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(sv.form, 11, 0, true).
+		AddItem(sv.progress, 0, 1, false).
+		AddItem(sv.results, 0, 2, false)
+
+	sv.setupUI()
+	return sv
+}
+
+func (sv *SearchViewer) setupUI() {
+	sv.form.
+		AddInputField("From", "-1h", 40, nil, nil).
+		AddInputField("To", "latest", 40, nil, nil).
+		AddInputField("Filter", "", 0, nil, nil).
+		AddButton("Search", sv.start).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle(fmt.Sprintf(" Search - %s (times, earliest, latest or @offset) ", sv.topic)).
+		SetTitleAlign(tview.AlignLeft)
+
+	sv.progress.SetBorder(true).
+		SetTitle(" Progress ").
+		SetTitleAlign(tview.AlignLeft)
+	sv.progress.SetFixed(1, 0)
+
+	sv.results.SetBorder(true).
+		SetTitle(" Matches ").
+		SetTitleAlign(tview.AlignLeft)
+
+	sv.SetInputCapture(sv.handleInput)
+}
+
+func (sv *SearchViewer) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	if sv.form.HasFocus() {
+		if event.Key() == tcell.KeyEscape {
+			sv.app.SetFocus(sv.results)
+			return nil
+		}
+		return event
+	}
+
+	switch event.Key() {
+	case tcell.KeyEscape:
+		sv.Stop()
+		sv.app.RemovePage("search")
+		return nil
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'e':
+			sv.app.SetFocus(sv.form)
+			return nil
+		case 'c':
+			sv.Stop()
+			return nil
+		}
+	}
+	return event
+}
+
+// start parses the form and launches a new search, cancelling any running one
+func (sv *SearchViewer) start() {
+	now := time.Now()
+	from, err := kafka.ParsePosition(sv.form.GetFormItemByLabel("From").(*tview.InputField).GetText(), now)
+	if err != nil {
+		sv.showError(fmt.Errorf("from: %w", err))
+		return
+	}
+	to, err := kafka.ParsePosition(sv.form.GetFormItemByLabel("To").(*tview.InputField).GetText(), now)
+	if err != nil {
+		sv.showError(fmt.Errorf("to: %w", err))
+		return
+	}
+	f, err := filter.ParseAt(sv.form.GetFormItemByLabel("Filter").(*tview.InputField).GetText(), now)
+	if err != nil {
+		sv.showError(fmt.Errorf("filter: %w", err))
+		return
+	}
+
+	sv.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sv.mutex.Lock()
+	sv.search = nil
+	sv.cancel = cancel
+	sv.shown = 0
+	sv.mutex.Unlock()
+
+	sv.results.Clear()
+	fmt.Fprint(sv.results, "[yellow]Resolving offsets...[-]\n")
+	sv.app.SetFocus(sv.results)
+
+	// resolving offsets takes a few requests per partition, keep them off
+	// the UI goroutine
+	go func() {
+		search, err := sv.client.NewSearch(sv.topic, from, to, f)
+		if err != nil {
+			sv.app.QueueUpdateDraw(func() {
+				if ctx.Err() == nil {
+					sv.showError(err)
+				}
+			})
+			return
+		}
+
+		sv.mutex.Lock()
+		if ctx.Err() != nil {
+			sv.mutex.Unlock()
+			return
+		}
+		sv.search = search
+		sv.mutex.Unlock()
+
+		sv.app.QueueUpdateDraw(func() {
+			sv.results.Clear()
+		})
+		go sv.monitorProgress(ctx, search)
+		search.Run(ctx, sv.writeMatch)
+		sv.app.QueueUpdateDraw(func() {
+			sv.updateProgress(search)
+		})
+	}()
+}
+
+// Stop cancels the running search, if any
+func (sv *SearchViewer) Stop() {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+	if sv.cancel != nil {
+		sv.cancel()
+		sv.cancel = nil
+	}
+}
+
+func (sv *SearchViewer) monitorProgress(ctx context.Context, search *kafka.Search) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sv.app.QueueUpdateDraw(func() {
+				sv.updateProgress(search)
+			})
+			if search.Progress().Done {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (sv *SearchViewer) updateProgress(search *kafka.Search) {
+	progress := search.Progress()
+
+	sv.progress.Clear()
+	for col, header := range []string{"Partition", "Start", "End", "Scanned", "Matched", "Progress", "Status"} {
+		sv.progress.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	for row, partition := range progress.Partitions {
+		status := "Scanning"
+		color := tcell.ColorWhite
+		switch {
+		case partition.Err != nil:
+			status = partition.Err.Error()
+			color = tcell.ColorRed
+		case partition.Done:
+			status = "Done"
+			color = tcell.ColorGreen
+		}
+
+		cells := []string{
+			fmt.Sprintf("%d", partition.Partition),
+			fmt.Sprintf("%d", partition.Start),
+			fmt.Sprintf("%d", partition.End),
+			fmt.Sprintf("%d", partition.Scanned),
+			fmt.Sprintf("%d", partition.Matched),
+			percent(partition.Scanned, partition.End-partition.Start, partition.Done),
+			status,
+		}
+		for col, content := range cells {
+			cell := tview.NewTableCell(content).SetExpansion(1)
+			if col == 6 {
+				cell.SetTextColor(color)
+			}
+			sv.progress.SetCell(row+1, col, cell)
+		}
+	}
+
+	state := fmt.Sprintf("ETA %s", progress.ETA().Round(time.Second))
+	switch {
+	case progress.Done:
+		state = fmt.Sprintf("finished in %s", progress.Elapsed.Round(time.Millisecond))
+	case sv.cancelled(search):
+		state = "cancelled"
+	}
+	sv.progress.SetTitle(fmt.Sprintf(" Progress - %d/%d scanned, %d matched, %s ",
+		progress.Scanned, progress.Total, progress.Matched, state))
+}
+
+func (sv *SearchViewer) cancelled(search *kafka.Search) bool {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+	return sv.search != search || sv.cancel == nil
+}
+
+func percent(done, total int64, finished bool) string {
+	if total == 0 || finished {
+		return "100%"
+	}
+	return fmt.Sprintf("%d%%", done*100/total)
+}
+
+func (sv *SearchViewer) writeMatch(msg models.Message) {
+	sv.mutex.Lock()
+	sv.shown++
+	shown := sv.shown
+	sv.mutex.Unlock()
+
+	if shown > maxDisplayedMatches {
+		return
+	}
+
+	sv.app.QueueUpdateDraw(func() {
+		fmt.Fprintf(sv.results, "[green]%s [partition-%d@%d][-] %s %s\n",
+			msg.Timestamp.Format("2006-01-02 15:04:05"),
+			msg.Partition,
+			msg.Offset,
+			tview.Escape(msg.KeyText()),
+			tview.Escape(msg.ValueText()))
+		if shown == maxDisplayedMatches {
+			fmt.Fprintf(sv.results, "[yellow]Only the first %d matches are displayed[-]\n", maxDisplayedMatches)
+		}
+	})
+}
+
+func (sv *SearchViewer) showError(err error) {
+	sv.results.Clear()
+	fmt.Fprintf(sv.results, "[red]%s[-]\n", tview.Escape(err.Error()))
+}
@@ -39,31 +39,52 @@ func NewTopBar(controlsList []controls.Control) *TopBar {
 	return topBar
 }
 
-// setupControls configures the controls display in vertical layout
+// setupControls configures the controls display in vertical layout, flowing
+// into additional columns when they do not fit the bar's height
 func (t *TopBar) setupControls() {
-	var controlsText strings.Builder
+	rowsPerColumn := t.GetHeight() - 1 // first row is padding
+
+	// Split controls into columns and find each column's widths for alignment
+	var columns [][]controls.Control
+	for start := 0; start < len(t.controls); start += rowsPerColumn {
+		end := min(start+rowsPerColumn, len(t.controls))
+		columns = append(columns, t.controls[start:end])
+	}
 
-	// Find the maximum key length for alignment
-	maxKeyLength := 0
-	for _, control := range t.controls {
-		if len(control.Key) > maxKeyLength {
-			maxKeyLength = len(control.Key)
+	keyWidths := make([]int, len(columns))
+	descWidths := make([]int, len(columns))
+	for i, column := range columns {
+		for _, control := range column {
+			keyWidths[i] = max(keyWidths[i], len(control.Key))
+			descWidths[i] = max(descWidths[i], len(control.Description))
 		}
 	}
 
+	var controlsText strings.Builder
+
 	// Add some padding at the top
 	controlsText.WriteString("\n")
 
-	// Create vertical list of controls with aligned descriptions
-	for i, control := range t.controls {
-		if i > 0 {
+	// Create vertical lists of controls with aligned descriptions
+	for row := 0; row < rowsPerColumn && row < len(t.controls); row++ {
+		if row > 0 {
 			controlsText.WriteString("\n")
 		}
-		controlsText.WriteString("  ") // Left padding
 
-		// Format with aligned descriptions
-		padding := strings.Repeat(" ", maxKeyLength-len(control.Key))
-		controlsText.WriteString("[yellow]<" + control.Key + ">" + padding + "[white]    " + control.Description)
+		for i, column := range columns {
+			if row >= len(column) {
+				continue
+			}
+			control := column[row]
+			controlsText.WriteString("  ") // Left padding
+
+			// Format with aligned descriptions
+			padding := strings.Repeat(" ", keyWidths[i]-len(control.Key))
+			controlsText.WriteString("[yellow]<" + control.Key + ">" + padding + "[white]    " + control.Description)
+			if i < len(columns)-1 {
+				controlsText.WriteString(strings.Repeat(" ", descWidths[i]-len(control.Description)+2))
+			}
+		}
 	}
 
 	t.controlsView.SetText(controlsText.String())
@@ -1,9 +1,16 @@
 package topics
 
 import (
+	"github.com/clemsau/kafe/internal/ui/batches"
+	"github.com/clemsau/kafe/internal/ui/compacted"
 	"github.com/clemsau/kafe/internal/ui/consumer_groups"
+	"github.com/clemsau/kafe/internal/ui/copyjob"
+	"github.com/clemsau/kafe/internal/ui/count"
 	"github.com/clemsau/kafe/internal/ui/dialog"
 	"github.com/clemsau/kafe/internal/ui/messages"
+	"github.com/clemsau/kafe/internal/ui/produce"
+	"github.com/clemsau/kafe/internal/ui/search"
+	"github.com/clemsau/kafe/internal/ui/truncate"
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -24,23 +31,113 @@ func (h *TopicTableHandler) Handle(event *tcell.EventKey) *tcell.EventKey {
 	switch event.Key() {
 	case tcell.KeyRune:
 		switch event.Rune() {
+		case ' ':
+			h.table.ToggleMark()
+			return nil
 		case '/':
 			h.table.searchBar.Activate()
 			return nil
+		case 'h':
+			h.table.ShowChart()
+			return nil
+		case 'w':
+			h.table.ShowWarnings()
+			return nil
+		case 'W':
+			h.table.ToggleWatch()
+			return nil
+		case 'l':
+			h.table.ShowWatchList()
+			return nil
 		case 'g':
 			selectedRow, _ := h.table.GetSelection()
 			if selectedRow > 0 {
 				topic := h.table.GetCell(selectedRow, 0).Text
-				viewer := consumer_groups.NewGroupViewer(h.table.app, h.table.client, topic)
+				viewer := consumer_groups.NewGroupViewer(h.table.app, h.table.client, topic, h.table.history)
 				h.table.app.AddPage("consumer-groups", viewer, true)
 			}
 			return nil
+		case 'p':
+			selectedRow, _ := h.table.GetSelection()
+			if selectedRow > 0 {
+				topic := h.table.GetCell(selectedRow, 0).Text
+				produceDialog := produce.NewProduceDialog(h.table.app, h.table.client, topic, nil)
+				h.table.app.AddPage("produce", produceDialog, true)
+			}
+			return nil
+		case 'P':
+			selectedRow, _ := h.table.GetSelection()
+			if selectedRow > 0 {
+				topic := h.table.GetCell(selectedRow, 0).Text
+				bulkDialog := produce.NewBulkDialog(h.table.app, h.table.client, topic)
+				h.table.app.AddPage("bulk-produce", bulkDialog, true)
+			}
+			return nil
+		case 'C':
+			selectedRow, _ := h.table.GetSelection()
+			if selectedRow > 0 {
+				topic := h.table.GetCell(selectedRow, 0).Text
+				copyDialog := copyjob.NewCopyDialog(h.table.app, h.table.client, topic)
+				h.table.app.AddPage("copy", copyDialog, true)
+			}
+			return nil
+		case 's':
+			selectedRow, _ := h.table.GetSelection()
+			if selectedRow > 0 {
+				topic := h.table.GetCell(selectedRow, 0).Text
+				viewer := search.NewSearchViewer(h.table.app, h.table.client, topic)
+				h.table.app.AddPage("search", viewer, true)
+			}
+			return nil
+		case 'k':
+			selectedRow, _ := h.table.GetSelection()
+			if selectedRow > 0 {
+				topic := h.table.GetCell(selectedRow, 0).Text
+				viewer := compacted.NewKeyViewer(h.table.app, h.table.client, topic)
+				h.table.app.AddPage("keys", viewer, true)
+				if err := viewer.Start(); err != nil {
+					dialog.ShowError(h.table.app, err.Error())
+					h.table.app.RemovePage("keys")
+				}
+			}
+			return nil
+		case 'c':
+			selectedRow, _ := h.table.GetSelection()
+			if selectedRow > 0 {
+				topic := h.table.GetCell(selectedRow, 0).Text
+				viewer := count.NewCountViewer(h.table.app, h.table.client, topic)
+				h.table.app.AddPage("count", viewer, true)
+				if err := viewer.Start(); err != nil {
+					dialog.ShowError(h.table.app, err.Error())
+					h.table.app.RemovePage("count")
+				}
+			}
+			return nil
+		case 'T':
+			selectedRow, _ := h.table.GetSelection()
+			if selectedRow > 0 {
+				topic := h.table.GetCell(selectedRow, 0).Text
+				truncateDialog := truncate.NewTruncateDialog(h.table.app, h.table.client, topic)
+				h.table.app.AddPage("truncate", truncateDialog, true)
+			}
+			return nil
+		case 'b':
+			selectedRow, _ := h.table.GetSelection()
+			if selectedRow > 0 {
+				topic := h.table.GetCell(selectedRow, 0).Text
+				viewer := batches.NewBatchViewer(h.table.app, h.table.client, topic)
+				h.table.app.AddPage("batches", viewer, true)
+			}
+			return nil
 		}
 	case tcell.KeyEnter:
 		selectedRow, _ := h.table.GetSelection()
 		if selectedRow > 0 {
-			topic := h.table.GetCell(selectedRow, 0).Text
-			viewer := messages.NewMessageViewer(h.table.app, h.table.client, topic)
+			topics := h.table.MarkedTopics()
+			if len(topics) == 0 {
+				topics = []string{h.table.GetCell(selectedRow, 0).Text}
+			}
+			viewer := messages.NewMessageViewer(h.table.app, h.table.client, topics)
 			h.table.app.AddPage("messages", viewer, true)
 			if err := viewer.Start(); err != nil {
 				dialog.ShowError(h.table.app, err.Error())
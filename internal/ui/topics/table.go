@@ -3,47 +3,72 @@ package topics
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/lint"
+	"github.com/clemsau/kafe/internal/metrics"
 	"github.com/clemsau/kafe/internal/models"
 	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/chart"
+	"github.com/clemsau/kafe/internal/ui/consumer_groups"
 	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/dialog"
 	"github.com/clemsau/kafe/internal/ui/topbar"
-	"github.com/clemsau/kafe/internal/ui/utils"
+	"github.com/clemsau/kafe/internal/ui/warnings"
+	"github.com/clemsau/kafe/internal/ui/watchlist"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
+const (
+	// watchRefreshInterval is how often the watched topics are fetched
+	watchRefreshInterval = time.Second
+	// fullRefreshTicks is how many watch refreshes a refresh of the topic
+	// list and of the visible topics takes
+	fullRefreshTicks = 2
+)
+
 // Table represents the topics table view
 type Table struct {
 	*tview.Table
 	app        *ui.App
 	client     *kafka.Client
 	cache      *models.TopicCache
+	history    *metrics.Store
+	watcher    *watchlist.Watcher
 	updateChan chan []models.TopicInfo
 	headers    []string
 	searchBar  *SearchBar
 	topBar     *topbar.TopBar
 	layout     *tview.Flex
+	// marked holds the topics selected for a merged message view
+	marked map[string]bool
 }
 
 // NewTable creates a new topics table
-func NewTable(app *ui.App, client *kafka.Client, cache *models.TopicCache) *tview.Flex {
+func NewTable(app *ui.App, client *kafka.Client, cache *models.TopicCache, history *metrics.Store, watcher *watchlist.Watcher) *tview.Flex {
 	table := &Table{
 		Table:      tview.NewTable().SetSelectable(true, false),
 		app:        app,
 		client:     client,
 		cache:      cache,
+		history:    history,
+		watcher:    watcher,
 		updateChan: make(chan []models.TopicInfo),
+		marked:     make(map[string]bool),
 		headers: []string{
 			"Topic",
 			"Partitions",
 			"Replicas",
 			"Status",
-			"Messages",
-			"Throughput (msg/s)",
+			"Offset span",
+			"High watermark",
+			"Last stable",
+			"Throughput (offsets/s)",
+			"Throughput (5m)",
 		},
 	}
 
@@ -51,7 +76,20 @@ func NewTable(app *ui.App, client *kafka.Client, cache *models.TopicCache) *tvie
 
 	table.topBar = topbar.NewTopBar([]controls.Control{
 		{Key: "Enter", Description: "view messages"},
+		{Key: "Space", Description: "mark for merged view"},
 		{Key: "g", Description: "consumer groups"},
+		{Key: "s", Description: "search history"},
+		{Key: "b", Description: "record batches"},
+		{Key: "k", Description: "latest value per key"},
+		{Key: "c", Description: "exact record count"},
+		{Key: "h", Description: "throughput chart"},
+		{Key: "w", Description: "warnings"},
+		{Key: "W", Description: "watch/unwatch"},
+		{Key: "l", Description: "watch list"},
+		{Key: "T", Description: "truncate partitions"},
+		{Key: "p", Description: "produce"},
+		{Key: "P", Description: "produce from file"},
+		{Key: "C", Description: "copy to topic"},
 		{Key: "/", Description: "search"},
 		{Key: "Home/End", Description: "first/last"},
 		{Key: "q", Description: "quit"},
@@ -116,7 +154,10 @@ func (t *Table) UpdateTable(topics []models.TopicInfo) {
 			fmt.Sprintf("%d", topic.Replicas),
 			topic.Status,
 			fmt.Sprintf("%d", topic.Messages),
+			fmt.Sprintf("%d", topic.HighWatermark),
+			fmt.Sprintf("%d", topic.LastStable),
 			fmt.Sprintf("%.1f", topic.Throughput),
+			t.throughputSparkline(topic.Name),
 		}
 
 		for col, content := range cells {
@@ -124,14 +165,28 @@ func (t *Table) UpdateTable(topics []models.TopicInfo) {
 				SetAlign(tview.AlignLeft).
 				SetExpansion(1)
 
+			if col == 0 && t.app.WatchList().HasTopic(topic.Name) {
+				cell.SetTextColor(ui.WatchedColor)
+			}
 			if col == 3 { // Status column
-				cell.SetTextColor(utils.GetStatusColor(topic.Status))
+				cell.SetTextColor(t.app.Rules().Color(topic.Status))
+			}
+			if col == 6 && topic.LastStable < topic.HighWatermark { // open transactions
+				cell.SetTextColor(tcell.ColorYellow)
+			}
+			if col == 8 {
+				cell.SetTextColor(tcell.ColorGreen).SetExpansion(0)
+			}
+			if t.marked[topic.Name] {
+				cell.SetAttributes(tcell.AttrBold).SetBackgroundColor(tcell.ColorDarkMagenta)
 			}
 
 			t.SetCell(row+1, col, cell)
 		}
 	}
 
+	t.updateTitle()
+
 	// Restore selection
 	maxRow := len(topics)
 	if currentRow > 0 && currentRow <= maxRow {
@@ -141,6 +196,28 @@ func (t *Table) UpdateTable(topics []models.TopicInfo) {
 	}
 }
 
+// throughputSparkline renders the recent produce rate of a topic
+func (t *Table) throughputSparkline(topic string) string {
+	rates := t.history.Rates(metrics.TopicKey(metrics.TopicOffsets, topic), chart.SparklineWindow)
+	return chart.SeriesSparkline(rates, chart.SparklineWidth)
+}
+
+// ShowChart opens the throughput chart of the selected topic
+func (t *Table) ShowChart() {
+	selectedRow, _ := t.GetSelection()
+	if selectedRow <= 0 {
+		return
+	}
+
+	topic := t.GetCell(selectedRow, 0).Text
+	key := metrics.TopicKey(metrics.TopicOffsets, topic)
+	page := chart.NewChartPage(t.app, t.history, "Throughput - "+topic, " offsets/s", func(window time.Duration) []metrics.Sample {
+		return t.history.Rates(key, window)
+	})
+	t.app.AddPage("chart", page, true)
+	page.Start()
+}
+
 // StartMonitoring starts the topic monitoring goroutine
 func (t *Table) StartMonitoring() {
 	go t.monitorTopics()
@@ -157,17 +234,35 @@ func (t *Table) StartMonitoring() {
 	}()
 }
 
-// monitorTopics periodically fetches topic information
+// monitorTopics periodically fetches topic information. The watched topics
+// are fetched on every tick, the visible ones every fullRefreshTicks ticks.
 func (t *Table) monitorTopics() {
-	tickRateSeconds := 2
-	ticker := time.NewTicker(time.Duration(tickRateSeconds) * time.Second)
+	ticker := time.NewTicker(watchRefreshInterval)
 	defer ticker.Stop()
 
+	tick := 0
 	for range ticker.C {
-		topics, err := t.client.Topics()
+		tick++
+		if tick%fullRefreshTicks != 0 {
+			if watched := t.app.WatchList().Topics(); len(watched) > 0 {
+				t.fetchTopics(watched)
+				t.updateChan <- t.filteredTopics(t.searchBar.GetFilterText())
+			}
+			continue
+		}
+
+		topics, err := t.client.ListTopics()
 		if err != nil {
 			continue
 		}
+		if evicted := t.cache.Reconcile(topics); len(evicted) > 0 {
+			for _, topic := range evicted {
+				t.history.RemoveTopic(topic)
+			}
+			t.app.QueueUpdateDraw(func() {
+				t.unmark(evicted)
+			})
+		}
 
 		// Update visible topics
 		_, _, _, height := t.GetInnerRect()
@@ -176,6 +271,10 @@ func (t *Table) monitorTopics() {
 		visibleStart := rowOffset + 1
 		visibleEnd := visibleStart + visibleRows
 
+		// Only fetch detailed info for visible or watched topics, or if never
+		// fetched
+		watchList := t.app.WatchList()
+		var toFetch []string
 		for i, topic := range topics {
 			topicRow := i + 1 // account for header
 			cachedInfo, exists := t.cache.Get(topic)
@@ -186,57 +285,166 @@ func (t *Table) monitorTopics() {
 				}
 			}
 
-			// Only fetch detailed info for visible topics or if never fetched
-			if (topicRow >= visibleStart && topicRow <= visibleEnd) || cachedInfo.LastUpdate.IsZero() {
-				info, err := t.client.GetTopicInfo(topic)
-				if err != nil {
-					log.Printf("Error fetching info for topic %s: %v", topic, err)
-					continue
-				}
-
-				if prev, exists := t.cache.GetPreviousMessages(topic); exists {
-					messageDelta := info.Messages - prev
-					info.Throughput = float64(messageDelta) / float64(tickRateSeconds)
-				}
-
-				t.cache.SetPreviousMessages(topic, info.Messages)
-
-				info.LastUpdate = time.Now()
-				t.cache.UpsertTopic(info)
+			if (topicRow >= visibleStart && topicRow <= visibleEnd) || cachedInfo.LastUpdate.IsZero() || watchList.HasTopic(topic) {
+				toFetch = append(toFetch, topic)
 				continue
 			}
 
 			t.cache.UpsertTopic(cachedInfo)
 		}
 
-		filterText := t.searchBar.GetFilterText()
-		if filterText != "" {
-			filtered := make([]models.TopicInfo, 0)
-			for _, topic := range t.cache.GetSortedTopics() {
-				if strings.Contains(strings.ToLower(topic.Name), strings.ToLower(filterText)) {
-					filtered = append(filtered, topic)
-				}
-			}
-			t.updateChan <- filtered
-			continue
-		}
-		t.updateChan <- t.cache.GetSortedTopics()
+		t.fetchTopics(toFetch)
+		t.updateChan <- t.filteredTopics(t.searchBar.GetFilterText())
 	}
 }
 
-// ApplyFilter filters the table based on search text
-func (t *Table) ApplyFilter(filterText string) {
-	topics := t.cache.GetSortedTopics()
-	if filterText == "" {
-		t.UpdateTable(topics)
+// fetchTopics fetches the information of topics into the cache and records
+// their offsets in the history
+func (t *Table) fetchTopics(topics []string) {
+	infos, err := t.client.GetTopicInfos(topics)
+	if err != nil {
+		log.Printf("Error fetching topic info: %v", err)
+	}
+	for _, info := range infos {
+		info.LastUpdate = time.Now()
+		info.Status = t.app.Rules().TopicStatus(info)
+		key := metrics.TopicKey(metrics.TopicOffsets, info.Name)
+		t.history.Record(key, info.LastUpdate, float64(info.HighWatermark))
+		info.Throughput, _ = t.history.LastRate(key)
+
+		t.cache.UpsertTopic(info)
+	}
+}
+
+// ToggleMark marks or unmarks the selected topic for a merged message view
+func (t *Table) ToggleMark() {
+	selectedRow, _ := t.GetSelection()
+	if selectedRow <= 0 {
 		return
 	}
 
-	filtered := make([]models.TopicInfo, 0)
+	topic := t.GetCell(selectedRow, 0).Text
+	if t.marked[topic] {
+		delete(t.marked, topic)
+	} else {
+		t.marked[topic] = true
+	}
+
+	for col := 0; col < t.GetColumnCount(); col++ {
+		cell := t.GetCell(selectedRow, col)
+		if t.marked[topic] {
+			cell.SetAttributes(tcell.AttrBold).SetBackgroundColor(tcell.ColorDarkMagenta)
+		} else {
+			cell.SetAttributes(tcell.AttrNone).SetBackgroundColor(tcell.ColorDefault)
+		}
+	}
+	t.updateTitle()
+}
+
+// unmark forgets the marks of evicted topics
+func (t *Table) unmark(topics []string) {
 	for _, topic := range topics {
-		if strings.Contains(strings.ToLower(topic.Name), strings.ToLower(filterText)) {
-			filtered = append(filtered, topic)
+		delete(t.marked, topic)
+	}
+	t.updateTitle()
+}
+
+// MarkedTopics returns the marked topics in name order
+func (t *Table) MarkedTopics() []string {
+	topics := make([]string, 0, len(t.marked))
+	for topic := range t.marked {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func (t *Table) updateTitle() {
+	title := "Topics"
+	if len(t.marked) > 0 {
+		title += fmt.Sprintf(" - %d marked for merged view", len(t.marked))
+	}
+	if unhealthy := t.watcher.Unhealthy(); unhealthy > 0 {
+		title += fmt.Sprintf(" - [red]%d watched unhealthy, l to see the alerts[-]", unhealthy)
+	}
+	t.SetTitle(title)
+}
+
+// SelectTopic selects the row of a topic, clearing the search filter when
+// it hides the topic
+func (t *Table) SelectTopic(name string) {
+	if !strings.Contains(strings.ToLower(name), strings.ToLower(t.searchBar.GetFilterText())) {
+		t.searchBar.SetText("")
+	}
+	for row := 1; row < t.GetRowCount(); row++ {
+		if t.GetCell(row, 0).Text == name {
+			t.Select(row, 0)
+			return
 		}
 	}
-	t.UpdateTable(filtered)
+}
+
+// ToggleWatch adds the selected topic to the watch list, or removes it
+func (t *Table) ToggleWatch() {
+	selectedRow, _ := t.GetSelection()
+	if selectedRow <= 0 {
+		return
+	}
+
+	cell := t.GetCell(selectedRow, 0)
+	watched, err := t.app.WatchList().ToggleTopic(cell.Text)
+	if err != nil {
+		dialog.ShowError(t.app, err.Error())
+	}
+	if watched {
+		cell.SetTextColor(ui.WatchedColor)
+	} else {
+		cell.SetTextColor(tcell.ColorWhite)
+	}
+}
+
+// ShowWatchList opens the watch list page, whose rows lead to the topic or
+// the consumer groups of the topic
+func (t *Table) ShowWatchList() {
+	view := watchlist.NewWatchView(t.app, t.cache, t.history, t.watcher, func(topic, group string) {
+		t.SelectTopic(topic)
+		if group != "" {
+			groups := consumer_groups.NewGroupViewer(t.app, t.client, topic, t.history)
+			t.app.AddPage("consumer-groups", groups, true)
+		}
+	})
+	t.app.AddPage("watch-list", view, true)
+	view.Start()
+}
+
+// ShowWarnings opens the warnings page, whose results lead to the topic or
+// the consumer groups of the topic
+func (t *Table) ShowWarnings() {
+	viewer := warnings.NewWarningsViewer(t.app, t.client, func(result lint.Result) {
+		t.SelectTopic(result.Topic)
+		if result.Group != "" {
+			groups := consumer_groups.NewGroupViewer(t.app, t.client, result.Topic, t.history)
+			t.app.AddPage("consumer-groups", groups, true)
+		}
+	})
+	t.app.AddPage("warnings", viewer, true)
+	viewer.Start()
+}
+
+// ApplyFilter filters the table based on search text
+func (t *Table) ApplyFilter(filterText string) {
+	t.UpdateTable(t.filteredTopics(filterText))
+}
+
+// filteredTopics returns a snapshot of the cached topics whose name
+// contains filterText
+func (t *Table) filteredTopics(filterText string) []models.TopicInfo {
+	if filterText == "" {
+		return t.cache.GetSortedTopics()
+	}
+
+	filterText = strings.ToLower(filterText)
+	return t.cache.GetFilteredTopics(func(topic models.TopicInfo) bool {
+		return strings.Contains(strings.ToLower(topic.Name), filterText)
+	})
 }
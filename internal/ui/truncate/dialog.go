@@ -0,0 +1,252 @@
+// Package truncate deletes the records of topic partitions before an
+// offset or a timestamp.
+package truncate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// TruncateDialog previews and runs the truncation of a topic's partitions
+type TruncateDialog struct {
+	*tview.Flex
+	form    *tview.Form
+	preview *tview.Table
+	status  *tview.TextView
+	topBar  *topbar.TopBar
+	app     *ui.App
+	client  *kafka.Client
+	topic   string
+
+	// plan is the previewed truncation and planned the form values it was
+	// computed from, so a changed form needs a new preview
+	plan    []kafka.PartitionTruncate
+	planned string
+	// previews counts the requested previews, only the latest one is shown
+	previews int
+}
+
+// NewTruncateDialog creates a truncation page for the topic
+func NewTruncateDialog(app *ui.App, client *kafka.Client, topic string) *TruncateDialog {
+	td := &TruncateDialog{
+		form:    tview.NewForm(),
+		preview: tview.NewTable(),
+		status: tview.NewTextView().
+			SetDynamicColors(true).
+			SetWrap(true),
+		app:    app,
+		client: client,
+		topic:  topic,
+	}
+
+	td.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Tab", Description: "next field"},
+		{Key: "Esc", Description: "close"},
+	})
+
+	td.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(td.topBar, td.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(td.form, 11, 0, true).
+		AddItem(td.preview, 0, 1, false).
+		AddItem(td.status, 4, 0, false)
+
+	td.setupUI()
+	return td
+}
+
+func (td *TruncateDialog) setupUI() {
+	td.form.
+		AddInputField("Partitions", "all", 30, nil, nil).
+		AddInputField("Delete before", "", 40, nil, nil).
+		AddInputField("Confirm", "", 40, nil, nil).
+		AddButton("Preview", td.showPreview).
+		AddButton("Truncate", td.truncate).
+		AddButton("Close", td.close).
+		SetCancelFunc(td.close).
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetLabelColor(tcell.ColorYellow).
+		SetBorder(true).
+		SetTitle(fmt.Sprintf(" Truncate - %s (partitions like 0,2,5-7 - @offset, time or latest) ", td.topic)).
+		SetTitleAlign(tview.AlignLeft)
+
+	td.preview.SetBorder(true).
+		SetTitle(" Preview ").
+		SetTitleAlign(tview.AlignLeft)
+	td.preview.SetFixed(1, 0)
+
+	td.status.SetBorder(true).
+		SetTitle(" Result ").
+		SetTitleAlign(tview.AlignLeft)
+
+	fmt.Fprint(td.status, "[gray]Preview the truncation, then type the topic name in Confirm and press Truncate. Deleted records cannot be recovered.[-]\n")
+
+	go func() {
+		if config, err := td.client.TopicConfig(td.topic, "cleanup.policy"); err == nil && config["cleanup.policy"] == "compact" {
+			td.app.QueueUpdateDraw(func() {
+				if td.previews > 0 {
+					return
+				}
+				td.report("[yellow]cleanup.policy=compact, the brokers reject deleting records of compact only topics[-]")
+			})
+		}
+	}()
+}
+
+func (td *TruncateDialog) text(label string) string {
+	return td.form.GetFormItemByLabel(label).(*tview.InputField).GetText()
+}
+
+// formKey identifies the form values a preview is computed from
+func (td *TruncateDialog) formKey() string {
+	return td.text("Partitions") + "\x00" + td.text("Delete before")
+}
+
+// showPreview resolves the target offset of each partition and shows how
+// many records would be removed. The offsets are resolved in the background.
+func (td *TruncateDialog) showPreview() {
+	td.plan = nil
+	td.previews++
+	preview := td.previews
+	key, partitions, before := td.formKey(), td.text("Partitions"), td.text("Delete before")
+	td.report("[yellow]Resolving offsets...[-]")
+
+	go func() {
+		plan, err := td.computePlan(partitions, before)
+		td.app.QueueUpdateDraw(func() {
+			if preview != td.previews {
+				return
+			}
+			if err != nil {
+				td.report("[red]%s[-]", tview.Escape(err.Error()))
+				return
+			}
+
+			td.plan = plan
+			td.planned = key
+			td.render(plan, false)
+
+			var total int64
+			for _, p := range plan {
+				total += p.Removed()
+			}
+			td.report("[yellow]%d records will be deleted from %d partitions. Type %s in Confirm to truncate.[-]",
+				total, len(plan), tview.Escape(td.topic))
+		})
+	}()
+}
+
+func (td *TruncateDialog) computePlan(partitionsText, before string) ([]kafka.PartitionTruncate, error) {
+	if before == "" {
+		return nil, fmt.Errorf("delete before needs an offset, a time or latest")
+	}
+	pos, err := kafka.ParsePosition(before, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("delete before: %w", err)
+	}
+
+	all, err := td.client.Partitions(td.topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+	partitions, err := kafka.ParsePartitions(partitionsText, all)
+	if err != nil {
+		return nil, err
+	}
+
+	return td.client.PlanTruncate(td.topic, partitions, pos)
+}
+
+// truncate runs the previewed truncation once confirmed
+func (td *TruncateDialog) truncate() {
+	switch {
+	case td.plan == nil || td.planned != td.formKey():
+		td.report("[red]Preview the truncation first[-]")
+		return
+	case td.text("Confirm") != td.topic:
+		td.report("[red]Type %s in Confirm to truncate[-]", tview.Escape(td.topic))
+		return
+	}
+
+	plan := td.plan
+	td.plan = nil
+	td.report("[yellow]Truncating...[-]")
+
+	go func() {
+		err := td.client.Truncate(td.topic, plan)
+		td.app.QueueUpdateDraw(func() {
+			td.render(plan, true)
+			td.form.GetFormItemByLabel("Confirm").(*tview.InputField).SetText("")
+			if err != nil {
+				td.report("[red]%s[-]", tview.Escape(err.Error()))
+				return
+			}
+			td.report("[green]Truncated %d partitions[-]", len(plan))
+		})
+	}()
+}
+
+// render shows the plan, with the outcome of each partition once truncated
+func (td *TruncateDialog) render(plan []kafka.PartitionTruncate, done bool) {
+	td.preview.Clear()
+	headers := []string{"Partition", "Log start", "End", "Delete before", "Records removed"}
+	if done {
+		headers = append(headers, "New log start", "Status")
+	}
+	for col, header := range headers {
+		td.preview.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	for row, p := range plan {
+		cells := []string{
+			fmt.Sprintf("%d", p.Partition),
+			fmt.Sprintf("%d", p.LogStart),
+			fmt.Sprintf("%d", p.End),
+			fmt.Sprintf("%d", p.Target),
+			fmt.Sprintf("%d", p.Removed()),
+		}
+		status, color := "", tcell.ColorWhite
+		if done {
+			status, color = "Truncated", tcell.ColorGreen
+			if p.Err != nil {
+				status, color = p.Err.Error(), tcell.ColorRed
+			}
+			lowWatermark := "-"
+			if p.Err == nil {
+				lowWatermark = fmt.Sprintf("%d", p.LowWatermark)
+			}
+			cells = append(cells, lowWatermark, status)
+		}
+
+		for col, content := range cells {
+			cell := tview.NewTableCell(content).SetExpansion(1)
+			if col == 4 && p.Removed() > 0 {
+				cell.SetTextColor(tcell.ColorRed)
+			}
+			if col == 6 {
+				cell.SetTextColor(color)
+			}
+			td.preview.SetCell(row+1, col, cell)
+		}
+	}
+}
+
+func (td *TruncateDialog) report(format string, args ...any) {
+	td.status.Clear()
+	fmt.Fprintf(td.status, format+"\n", args...)
+}
+
+func (td *TruncateDialog) close() {
+	td.app.RemovePage("truncate")
+}
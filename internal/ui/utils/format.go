@@ -0,0 +1,14 @@
+package utils
+
+import "fmt"
+
+// FormatBytes formats a size in bytes, e.g. 512 B, 3.2 KB or 1.5 MB
+func FormatBytes(bytes int) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
@@ -0,0 +1,168 @@
+// Package warnings shows the results of the lint checks run against the
+// cluster.
+package warnings
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/clemsau/kafe/internal/kafka"
+	"github.com/clemsau/kafe/internal/lint"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// severityColors are the colors of the severities
+var severityColors = map[lint.Severity]tcell.Color{
+	lint.Error:   tcell.ColorRed,
+	lint.Warning: tcell.ColorYellow,
+	lint.Info:    tcell.ColorWhite,
+}
+
+// WarningsViewer lists the problems found by the lint checks, the selected
+// one leading to its topic or consumer group
+type WarningsViewer struct {
+	*tview.Flex
+	table  *tview.Table
+	topBar *topbar.TopBar
+	app    *ui.App
+	client *kafka.Client
+	jump   func(result lint.Result)
+
+	mutex   sync.Mutex
+	running bool
+	results []lint.Result
+}
+
+// NewWarningsViewer creates a warnings page. jump is called with the
+// selected result on Enter, after the page is closed.
+func NewWarningsViewer(app *ui.App, client *kafka.Client, jump func(result lint.Result)) *WarningsViewer {
+	wv := &WarningsViewer{
+		table:  tview.NewTable().SetSelectable(true, false),
+		app:    app,
+		client: client,
+		jump:   jump,
+	}
+
+	wv.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Esc", Description: "back to topics"},
+		{Key: "Enter", Description: "go to topic or group"},
+		{Key: "r", Description: "check again"},
+		{Key: "q", Description: "quit"},
+	})
+
+	wv.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(wv.topBar, wv.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(wv.table, 0, 1, true)
+
+	wv.setupUI()
+	return wv
+}
+
+func (wv *WarningsViewer) setupUI() {
+	wv.table.SetBorder(true).
+		SetTitle(" Warnings ").
+		SetTitleAlign(tview.AlignLeft)
+	wv.table.SetFixed(1, 0)
+	wv.table.SetSelectedStyle(tcell.StyleDefault.
+		Background(tcell.ColorRoyalBlue).
+		Foreground(tcell.ColorWhite))
+
+	wv.SetInputCapture(wv.handleInput)
+}
+
+func (wv *WarningsViewer) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEscape:
+		wv.app.RemovePage("warnings")
+		return nil
+	case tcell.KeyEnter:
+		row, _ := wv.table.GetSelection()
+		wv.mutex.Lock()
+		results := wv.results
+		wv.mutex.Unlock()
+		if row > 0 && row <= len(results) {
+			result := results[row-1]
+			if result.Topic != "" {
+				wv.app.RemovePage("warnings")
+				wv.jump(result)
+			}
+		}
+		return nil
+	case tcell.KeyRune:
+		if event.Rune() == 'r' {
+			wv.Start()
+			return nil
+		}
+	}
+	return event
+}
+
+// Start collects the state of the cluster and runs the checks in the
+// background, unless they are already running
+func (wv *WarningsViewer) Start() {
+	wv.mutex.Lock()
+	if wv.running {
+		wv.mutex.Unlock()
+		return
+	}
+	wv.running = true
+	wv.mutex.Unlock()
+
+	wv.table.SetTitle(" Warnings - checking the cluster... ")
+	go func() {
+		state, err := wv.client.ClusterState()
+		var results []lint.Result
+		if state != nil {
+			results = lint.Run(state)
+		}
+
+		wv.app.QueueUpdateDraw(func() {
+			wv.mutex.Lock()
+			wv.running = false
+			wv.results = results
+			wv.mutex.Unlock()
+			wv.render(state, err)
+		})
+	}()
+}
+
+func (wv *WarningsViewer) render(state *kafka.ClusterState, err error) {
+	wv.table.Clear()
+	for col, header := range []string{"Severity", "Check", "Object", "Details"} {
+		wv.table.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	counts := make(map[lint.Severity]int)
+	for i, result := range wv.results {
+		counts[result.Severity]++
+		wv.table.SetCell(i+1, 0, tview.NewTableCell(result.Severity.String()).
+			SetTextColor(severityColors[result.Severity]))
+		wv.table.SetCell(i+1, 1, tview.NewTableCell(result.Check))
+		wv.table.SetCell(i+1, 2, tview.NewTableCell(result.Object()))
+		wv.table.SetCell(i+1, 3, tview.NewTableCell(result.Message).SetExpansion(3))
+	}
+	if len(wv.results) > 0 {
+		wv.table.Select(1, 0)
+	}
+
+	title := " Warnings"
+	if state != nil {
+		title += fmt.Sprintf(" - %d errors, %d warnings, %d info - checked at %s",
+			counts[lint.Error], counts[lint.Warning], counts[lint.Info], state.At.Format("15:04:05"))
+	}
+	if err != nil {
+		// joined errors are one per line
+		title += " - " + strings.ReplaceAll(err.Error(), "\n", "; ")
+	}
+	wv.table.SetTitle(title + " ")
+}
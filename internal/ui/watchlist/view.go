@@ -0,0 +1,266 @@
+package watchlist
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clemsau/kafe/internal/metrics"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/chart"
+	"github.com/clemsau/kafe/internal/ui/consumer_groups"
+	"github.com/clemsau/kafe/internal/ui/controls"
+	"github.com/clemsau/kafe/internal/ui/dialog"
+	"github.com/clemsau/kafe/internal/ui/topbar"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// refreshInterval is how often the watch list page is redrawn
+const refreshInterval = time.Second
+
+// entry is a row of the watch list, a topic when group is empty
+type entry struct {
+	topic string
+	group string
+}
+
+// WatchView shows the watched topics and groups of the context and the
+// alerts they raised
+type WatchView struct {
+	*tview.Flex
+	table   *tview.Table
+	alerts  *tview.TextView
+	topBar  *topbar.TopBar
+	app     *ui.App
+	cache   *models.TopicCache
+	history *metrics.Store
+	watcher *Watcher
+	jump    func(topic, group string)
+	entries []entry
+	cancel  context.CancelFunc
+}
+
+// NewWatchView creates a watch list page. jump is called with the topic, and
+// the group of group rows, of the selected row on Enter, after the page is
+// closed.
+func NewWatchView(app *ui.App, cache *models.TopicCache, history *metrics.Store, watcher *Watcher, jump func(topic, group string)) *WatchView {
+	wv := &WatchView{
+		table:   tview.NewTable().SetSelectable(true, false),
+		alerts:  tview.NewTextView().SetDynamicColors(true),
+		app:     app,
+		cache:   cache,
+		history: history,
+		watcher: watcher,
+		jump:    jump,
+	}
+
+	wv.topBar = topbar.NewTopBar([]controls.Control{
+		{Key: "Esc", Description: "back to topics"},
+		{Key: "Enter", Description: "go to topic or group"},
+		{Key: "W", Description: "unwatch"},
+		{Key: "q", Description: "quit"},
+	})
+
+	wv.Flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(wv.topBar, wv.topBar.GetHeight(), 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(wv.table, 0, 2, true).
+		AddItem(wv.alerts, 0, 1, false)
+
+	wv.setupUI()
+	return wv
+}
+
+func (wv *WatchView) setupUI() {
+	wv.table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Watch list - %s ", wv.app.CurrentContext())).
+		SetTitleAlign(tview.AlignLeft)
+	wv.table.SetFixed(1, 0)
+	wv.table.SetSelectedStyle(tcell.StyleDefault.
+		Background(tcell.ColorRoyalBlue).
+		Foreground(tcell.ColorWhite))
+
+	wv.alerts.SetBorder(true).
+		SetTitle(" Alerts ").
+		SetTitleAlign(tview.AlignLeft)
+
+	wv.SetInputCapture(wv.handleInput)
+}
+
+func (wv *WatchView) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEscape:
+		wv.Stop()
+		wv.app.RemovePage("watch-list")
+		return nil
+	case tcell.KeyEnter:
+		if e, ok := wv.selected(); ok {
+			wv.Stop()
+			wv.app.RemovePage("watch-list")
+			wv.jump(e.topic, e.group)
+		}
+		return nil
+	case tcell.KeyRune:
+		if event.Rune() == 'W' {
+			wv.unwatch()
+			return nil
+		}
+	}
+	return event
+}
+
+// selected returns the entry of the selected row
+func (wv *WatchView) selected() (entry, bool) {
+	row, _ := wv.table.GetSelection()
+	if row <= 0 || row > len(wv.entries) {
+		return entry{}, false
+	}
+	return wv.entries[row-1], true
+}
+
+func (wv *WatchView) unwatch() {
+	e, ok := wv.selected()
+	if !ok {
+		return
+	}
+
+	var err error
+	if e.group != "" {
+		_, err = wv.app.WatchList().ToggleGroup(e.group, e.topic)
+	} else {
+		_, err = wv.app.WatchList().ToggleTopic(e.topic)
+	}
+	if err != nil {
+		dialog.ShowError(wv.app, err.Error())
+	}
+	wv.render()
+}
+
+// Start draws the watch list and keeps it up to date until the page is
+// closed
+func (wv *WatchView) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	wv.cancel = cancel
+
+	wv.render()
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				wv.app.QueueUpdateDraw(wv.render)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the watch list
+func (wv *WatchView) Stop() {
+	if wv.cancel != nil {
+		wv.cancel()
+	}
+}
+
+func (wv *WatchView) render() {
+	currentRow, _ := wv.table.GetSelection()
+	wv.table.Clear()
+	for col, header := range []string{"Watched", "Status", "Lag", "Time behind", "Rate (1m)", "Trend (5m)", "Last update"} {
+		wv.table.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	list := wv.app.WatchList()
+	wv.entries = wv.entries[:0]
+	for _, topic := range list.Topics() {
+		wv.entries = append(wv.entries, entry{topic: topic})
+		wv.setRow(len(wv.entries), wv.topicCells(topic), tcell.ColorGreen)
+	}
+	for _, group := range list.Groups() {
+		wv.entries = append(wv.entries, entry{topic: group.Topic, group: group.Group})
+		wv.setRow(len(wv.entries), wv.groupCells(group.Group, group.Topic), tcell.ColorYellow)
+	}
+
+	if currentRow > 0 && currentRow <= len(wv.entries) {
+		wv.table.Select(currentRow, 0)
+	} else if len(wv.entries) > 0 {
+		wv.table.Select(1, 0)
+	}
+
+	var alerts strings.Builder
+	for _, alert := range wv.watcher.Alerts() {
+		fmt.Fprintf(&alerts, "%s  %s is [%s]%s[-]\n", alert.At.Format("15:04:05"), alert.Object(),
+			wv.app.Rules().Color(alert.Status).String(), tview.Escape(alert.Status))
+	}
+	wv.alerts.SetText(alerts.String())
+	wv.alerts.SetTitle(fmt.Sprintf(" Alerts - %d watched unhealthy ", wv.watcher.Unhealthy()))
+}
+
+// setRow sets the cells of a row, the status being the second one and the
+// sparkline the sixth one
+func (wv *WatchView) setRow(row int, cells []string, trendColor tcell.Color) {
+	for col, content := range cells {
+		cell := tview.NewTableCell(content).SetExpansion(1)
+		switch col {
+		case 0:
+			cell.SetTextColor(ui.WatchedColor)
+		case 1:
+			cell.SetTextColor(wv.app.Rules().Color(content))
+		case 5:
+			cell.SetTextColor(trendColor).SetExpansion(0)
+		}
+		wv.table.SetCell(row, col, cell)
+	}
+}
+
+func (wv *WatchView) topicCells(topic string) []string {
+	info, ok := wv.cache.Get(topic)
+	if !ok || info.LastUpdate.IsZero() {
+		return []string{"topic " + topic, "", "", "", "", "", ""}
+	}
+
+	key := metrics.TopicKey(metrics.TopicOffsets, topic)
+	rate := ""
+	if r, ok := wv.history.Rate(key, time.Minute); ok {
+		rate = fmt.Sprintf("%.1f offsets/s", r)
+	}
+	return []string{
+		"topic " + topic,
+		info.Status,
+		"",
+		"",
+		rate,
+		chart.SeriesSparkline(wv.history.Rates(key, chart.SparklineWindow), chart.SparklineWidth),
+		info.LastUpdate.Format("15:04:05"),
+	}
+}
+
+func (wv *WatchView) groupCells(group, topic string) []string {
+	name := fmt.Sprintf("group %s on %s", group, topic)
+	info, ok := wv.watcher.Group(group, topic)
+	if !ok {
+		return []string{name, wv.watcher.Status(topic, group), "", "", "", "", ""}
+	}
+
+	rate := ""
+	if r, ok := wv.history.Rate(metrics.GroupKey(metrics.GroupCommitted, group, topic), time.Minute); ok {
+		rate = fmt.Sprintf("%.1f msg/s", r)
+	}
+	return []string{
+		name,
+		info.Status,
+		fmt.Sprintf("%d", info.TotalLag),
+		consumer_groups.FormatTimeLag(info.TimeLag, info.TimeLagKnown),
+		rate,
+		chart.SeriesSparkline(wv.history.Samples(metrics.GroupKey(metrics.GroupLag, group, topic), chart.SparklineWindow), chart.SparklineWidth),
+		info.LastUpdate.Format("15:04:05"),
+	}
+}
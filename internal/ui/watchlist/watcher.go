@@ -0,0 +1,221 @@
+// Package watchlist refreshes the watched topics and consumer groups in the
+// background, raises alerts when they turn unhealthy, and shows them on a
+// dedicated page.
+package watchlist
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/clemsau/kafe/internal/metrics"
+	"github.com/clemsau/kafe/internal/models"
+	"github.com/clemsau/kafe/internal/rules"
+	"github.com/clemsau/kafe/internal/ui"
+	"github.com/clemsau/kafe/internal/ui/consumer_groups"
+	"github.com/clemsau/kafe/internal/watch"
+)
+
+const (
+	// checkInterval is how often the watch list is checked
+	checkInterval = 2 * time.Second
+	// maxAlerts is how many alerts are kept
+	maxAlerts = 100
+	// StatusNotConsuming is the status of a watched group no member of
+	// which consumes its topic anymore
+	StatusNotConsuming = "Not consuming"
+)
+
+// Alert records a watched topic or group turning unhealthy
+type Alert struct {
+	At     time.Time
+	Topic  string
+	Group  string
+	Status string
+}
+
+// Object describes what the alert is about
+func (a Alert) Object() string {
+	if a.Group != "" {
+		return fmt.Sprintf("group %s on %s", a.Group, a.Topic)
+	}
+	return "topic " + a.Topic
+}
+
+// Watcher follows the watched groups, whatever page is open, and compares
+// the statuses of the watched topics and groups to raise alerts. The
+// watched topics themselves are fetched by the topics table, and the groups
+// polled by the metrics store.
+type Watcher struct {
+	app     *ui.App
+	cache   *models.TopicCache
+	history *metrics.Store
+
+	mutex    sync.Mutex
+	groups   map[watch.Group]models.ConsumerGroupInfo
+	statuses map[string]string
+	alerts   []Alert
+}
+
+// NewWatcher creates a watcher of the watch list of the application
+func NewWatcher(app *ui.App, cache *models.TopicCache, history *metrics.Store) *Watcher {
+	return &Watcher{
+		app:      app,
+		cache:    cache,
+		history:  history,
+		groups:   make(map[watch.Group]models.ConsumerGroupInfo),
+		statuses: make(map[string]string),
+	}
+}
+
+// Start checks the watch list periodically
+func (w *Watcher) Start() {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			w.refresh()
+		}
+	}()
+}
+
+func (w *Watcher) refresh() {
+	list := w.app.WatchList()
+
+	for _, topic := range list.Topics() {
+		if info, ok := w.cache.Get(topic); ok && !info.LastUpdate.IsZero() {
+			w.check(Alert{Topic: topic, Status: info.Status})
+		}
+	}
+
+	watched := list.Groups()
+	byTopic := make(map[string][]watch.Group)
+	for _, group := range watched {
+		byTopic[group.Topic] = append(byTopic[group.Topic], group)
+	}
+
+	groups := make(map[watch.Group]models.ConsumerGroupInfo, len(watched))
+	for topic, entries := range byTopic {
+		w.history.TrackGroups(topic)
+		poll, ok := w.history.Groups(topic)
+		if !ok || poll.Err != nil {
+			// keep the last known state of the groups of the topic
+			w.mutex.Lock()
+			for _, entry := range entries {
+				if info, ok := w.groups[entry]; ok {
+					groups[entry] = info
+				}
+			}
+			w.mutex.Unlock()
+			continue
+		}
+
+		for _, info := range poll.Groups {
+			entry := watch.Group{Group: info.ID, Topic: topic}
+			if list.HasGroup(info.ID, topic) {
+				consumer_groups.Evaluate(w.app, w.history, &info)
+				groups[entry] = info
+			}
+		}
+		for _, entry := range entries {
+			status := StatusNotConsuming
+			if info, ok := groups[entry]; ok {
+				status = info.Status
+			}
+			w.check(Alert{Topic: topic, Group: entry.Group, Status: status})
+		}
+	}
+
+	w.mutex.Lock()
+	w.groups = groups
+	w.forgetUnwatched(list)
+	w.mutex.Unlock()
+}
+
+// forgetUnwatched drops the statuses of the topics and groups no longer
+// watched, so watching them again raises alerts anew. The caller holds the
+// lock.
+func (w *Watcher) forgetUnwatched(list *watch.List) {
+	watched := make(map[string]bool)
+	for _, topic := range list.Topics() {
+		watched[Alert{Topic: topic}.Object()] = true
+	}
+	for _, group := range list.Groups() {
+		watched[Alert{Topic: group.Topic, Group: group.Group}.Object()] = true
+	}
+	for key := range w.statuses {
+		if !watched[key] {
+			delete(w.statuses, key)
+		}
+	}
+}
+
+// check records the status of a watched topic or group, and raises an alert
+// when it turns unhealthy
+func (w *Watcher) check(alert Alert) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	key := alert.Object()
+	previous, seen := w.statuses[key]
+	w.statuses[key] = alert.Status
+	if rules.Healthy(alert.Status) || (seen && !rules.Healthy(previous)) {
+		return
+	}
+
+	alert.At = time.Now()
+	w.alerts = append(w.alerts, alert)
+	if len(w.alerts) > maxAlerts {
+		w.alerts = w.alerts[len(w.alerts)-maxAlerts:]
+	}
+}
+
+// Group returns the last state of a watched group, false before it was
+// fetched or when no member consumes its topic
+func (w *Watcher) Group(group, topic string) (models.ConsumerGroupInfo, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	info, ok := w.groups[watch.Group{Group: group, Topic: topic}]
+	return info, ok
+}
+
+// Status returns the last status of a watched topic or group, empty before
+// it was checked
+func (w *Watcher) Status(topic, group string) string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.statuses[Alert{Topic: topic, Group: group}.Object()]
+}
+
+// Alerts returns the alerts raised, the latest first
+func (w *Watcher) Alerts() []Alert {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	alerts := make([]Alert, len(w.alerts))
+	for i, alert := range w.alerts {
+		alerts[len(alerts)-1-i] = alert
+	}
+	return alerts
+}
+
+// Unhealthy returns how many watched topics and groups are unhealthy
+func (w *Watcher) Unhealthy() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	list := w.app.WatchList()
+	unhealthy := 0
+	for _, topic := range list.Topics() {
+		if status, ok := w.statuses[Alert{Topic: topic}.Object()]; ok && !rules.Healthy(status) {
+			unhealthy++
+		}
+	}
+	for _, group := range list.Groups() {
+		if status, ok := w.statuses[Alert{Topic: group.Topic, Group: group.Group}.Object()]; ok && !rules.Healthy(status) {
+			unhealthy++
+		}
+	}
+	return unhealthy
+}
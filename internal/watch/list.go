@@ -0,0 +1,248 @@
+// Package watch keeps the watch list of every context: the topics and
+// consumer groups the user pinned, persisted across sessions.
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Group is a consumer group watched on a topic
+type Group struct {
+	Group string `json:"group"`
+	Topic string `json:"topic"`
+}
+
+// Entries are the topics and groups watched on a context
+type Entries struct {
+	Topics []string `json:"topics,omitempty"`
+	Groups []Group  `json:"groups,omitempty"`
+}
+
+// state is the content of the watch list file
+type state struct {
+	Contexts map[string]*Entries `json:"contexts"`
+}
+
+// List is the watch list of a context, saved to its file on every change.
+// It is safe for concurrent use.
+type List struct {
+	mutex   sync.RWMutex
+	path    string
+	context string
+	state   state
+}
+
+// DefaultPath returns the default location of the watch list file, in
+// $XDG_STATE_HOME/kafe, by default ~/.local/state/kafe
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "kafe", "watch.json"), nil
+}
+
+// Load reads the watch list of a context from the file at path. A missing
+// file yields an empty list, and an empty path a list that is not saved.
+func Load(path, context string) (*List, error) {
+	l := &List{path: path, context: context}
+	if path != "" {
+		state, err := readState(path)
+		if err != nil {
+			return nil, err
+		}
+		l.state = state
+	}
+	if l.state.Contexts == nil {
+		l.state.Contexts = make(map[string]*Entries)
+	}
+	if l.state.Contexts[context] == nil {
+		l.state.Contexts[context] = &Entries{}
+	}
+	return l, nil
+}
+
+// readState reads the watch list file, a missing file being empty
+func readState(path string) (state, error) {
+	var s state
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read watch list: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("failed to parse watch list %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// entries returns the entries of the context. The caller holds the lock.
+func (l *List) entries() *Entries {
+	return l.state.Contexts[l.context]
+}
+
+// Topics returns the watched topics, sorted
+func (l *List) Topics() []string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return append([]string(nil), l.entries().Topics...)
+}
+
+// Groups returns the watched groups, sorted by topic then group
+func (l *List) Groups() []Group {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return append([]Group(nil), l.entries().Groups...)
+}
+
+// HasTopic tells whether a topic is watched
+func (l *List) HasTopic(topic string) bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	for _, watched := range l.entries().Topics {
+		if watched == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// HasGroup tells whether a group is watched on a topic
+func (l *List) HasGroup(group, topic string) bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	for _, watched := range l.entries().Groups {
+		if watched == (Group{Group: group, Topic: topic}) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleTopic watches a topic, or stops watching it when it is, and saves
+// the list. It returns whether the topic is now watched. The list is left
+// unchanged when it cannot be saved.
+func (l *List) ToggleTopic(topic string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries := l.entries().clone()
+	for i, watched := range entries.Topics {
+		if watched == topic {
+			entries.Topics = append(entries.Topics[:i], entries.Topics[i+1:]...)
+			if err := l.save(entries); err != nil {
+				return true, err
+			}
+			return false, nil
+		}
+	}
+	entries.Topics = append(entries.Topics, topic)
+	sort.Strings(entries.Topics)
+	if err := l.save(entries); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ToggleGroup watches a group on a topic, or stops watching it when it is,
+// and saves the list. It returns whether the group is now watched. The
+// list is left unchanged when it cannot be saved.
+func (l *List) ToggleGroup(group, topic string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries := l.entries().clone()
+	entry := Group{Group: group, Topic: topic}
+	for i, watched := range entries.Groups {
+		if watched == entry {
+			entries.Groups = append(entries.Groups[:i], entries.Groups[i+1:]...)
+			if err := l.save(entries); err != nil {
+				return true, err
+			}
+			return false, nil
+		}
+	}
+	entries.Groups = append(entries.Groups, entry)
+	sort.Slice(entries.Groups, func(i, j int) bool {
+		a, b := entries.Groups[i], entries.Groups[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Group < b.Group
+	})
+	if err := l.save(entries); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// clone returns a copy of the entries that can be changed without
+// affecting them
+func (e *Entries) clone() *Entries {
+	return &Entries{
+		Topics: append([]string(nil), e.Topics...),
+		Groups: append([]Group(nil), e.Groups...),
+	}
+}
+
+// save writes entries as the list of the context to the file, and makes
+// them the list once written. The file is read again and only the context
+// is replaced, so sessions on other contexts do not overwrite each other,
+// then written through a temporary file so a crash cannot leave it
+// truncated. The caller holds the lock.
+func (l *List) save(entries *Entries) error {
+	if l.path == "" {
+		l.state.Contexts[l.context] = entries
+		return nil
+	}
+
+	current, err := readState(l.path)
+	if err != nil {
+		return err
+	}
+	if current.Contexts == nil {
+		current.Contexts = make(map[string]*Entries)
+	}
+	current.Contexts[l.context] = entries
+
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode watch list: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to save watch list: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save watch list: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save watch list: %w", err)
+	}
+	l.state = current
+	return nil
+}
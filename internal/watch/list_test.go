@@ -0,0 +1,115 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListPersistsPerContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kafe", "watch.json")
+
+	local, err := Load(path, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, topic := range []string{"payments", "orders"} {
+		if watched, err := local.ToggleTopic(topic); err != nil || !watched {
+			t.Fatalf("toggling %s: %v, %v", topic, watched, err)
+		}
+	}
+	if _, err := local.ToggleGroup("billing", "orders"); err != nil {
+		t.Fatal(err)
+	}
+
+	prod, err := Load(path, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prod.Topics()) != 0 {
+		t.Errorf("prod watches %v, want nothing", prod.Topics())
+	}
+	if _, err := prod.ToggleTopic("audit"); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := Load(path, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topics := reloaded.Topics(); !reflect.DeepEqual(topics, []string{"orders", "payments"}) {
+		t.Errorf("reloaded topics are %v, want [orders payments]", topics)
+	}
+	if !reloaded.HasGroup("billing", "orders") || reloaded.HasGroup("billing", "payments") {
+		t.Errorf("reloaded groups are %v, want billing on orders", reloaded.Groups())
+	}
+}
+
+func TestToggleTwiceUnwatches(t *testing.T) {
+	list, err := Load("", "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list.ToggleTopic("orders")
+	if watched, _ := list.ToggleTopic("orders"); watched || list.HasTopic("orders") {
+		t.Errorf("orders is still watched")
+	}
+	list.ToggleGroup("billing", "orders")
+	if watched, _ := list.ToggleGroup("billing", "orders"); watched || len(list.Groups()) != 0 {
+		t.Errorf("groups are %v, want none", list.Groups())
+	}
+}
+
+func TestSaveKeepsOtherContexts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.json")
+
+	// two sessions opened at the same time on different contexts
+	local, err := Load(path, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod, err := Load(path, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.ToggleTopic("orders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prod.ToggleTopic("audit"); err != nil {
+		t.Fatal(err)
+	}
+
+	for context, want := range map[string][]string{"local": {"orders"}, "prod": {"audit"}} {
+		reloaded, err := Load(path, context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if topics := reloaded.Topics(); !reflect.DeepEqual(topics, want) {
+			t.Errorf("%s watches %v, want %v", context, topics, want)
+		}
+	}
+}
+
+func TestToggleKeepsListWhenSaveFails(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "kafe")
+	list, err := Load(filepath.Join(dir, "watch.json"), "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a file in place of the state directory makes every save fail
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if watched, err := list.ToggleTopic("orders"); err == nil || watched {
+		t.Errorf("ToggleTopic = %v, %v, want false and an error", watched, err)
+	}
+	if watched, err := list.ToggleGroup("billing", "orders"); err == nil || watched {
+		t.Errorf("ToggleGroup = %v, %v, want false and an error", watched, err)
+	}
+	if list.HasTopic("orders") || list.HasGroup("billing", "orders") {
+		t.Error("entries kept although the list could not be saved")
+	}
+}